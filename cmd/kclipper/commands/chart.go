@@ -1,10 +1,12 @@
 package commands
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/mattn/go-isatty"
@@ -18,8 +20,10 @@ import (
 	"github.com/macropower/kclipper/pkg/charttui"
 	"github.com/macropower/kclipper/pkg/crd"
 	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/helmrepo"
 	"github.com/macropower/kclipper/pkg/kclmodule/kclchart"
 	"github.com/macropower/kclipper/pkg/kclmodule/kclhelm"
+	"github.com/macropower/kclipper/pkg/paths"
 	"github.com/macropower/kclipper/pkg/schema"
 )
 
@@ -41,6 +45,9 @@ const (
 
   # Set chart configuration attributes
   kcl chart set --chart podinfo --overrides "targetRevision=6.7.1"
+
+  # List cached charts
+  kcl chart cache list
 `
 )
 
@@ -92,6 +99,13 @@ func NewChartCmd(logCfg *log.Config) *cobra.Command {
 	cmd.PersistentFlags().BoolVarP(args.quiet, "quiet", "q", false, "Run in quiet mode")
 	cmd.PersistentFlags().BoolVarP(args.vendor, "vendor", "V", false, "Run in vendor mode")
 	cmd.PersistentFlags().StringVar(args.maxExtractSize, "max_extract_size", "10Mi", "Maximum size of extracted charts")
+	cmd.PersistentFlags().StringVar(args.verify, "default_verify", os.Getenv("KCLIPPER_HELM_VERIFY"),
+		"Default chart provenance verification mode (NEVER, IF_POSSIBLE, ALWAYS)")
+	cmd.PersistentFlags().StringVar(args.keyring, "default_keyring", os.Getenv("KCLIPPER_HELM_KEYRING"),
+		"Default keyring used to verify chart provenance")
+	cmd.PersistentFlags().StringVar(args.indexTTL, "index_ttl",
+		cmp.Or(os.Getenv("KCLIPPER_HELM_INDEX_TTL"), helm.DefaultIndexCacheTTL.String()),
+		"Time for which cached Helm repository indexes are used without revalidation")
 
 	cmd.PersistentPreRunE = func(cc *cobra.Command, ccArgs []string) error {
 		if parent := cmd.Parent(); parent != nil && parent.PersistentPreRunE != nil {
@@ -106,6 +120,16 @@ func NewChartCmd(logCfg *log.Config) *cobra.Command {
 			return fmt.Errorf("%w: %w: max_extract_size: %w", ErrArgument, ErrInvalidArgument, err)
 		}
 
+		_, err = helmrepo.GetVerifyMode(*args.verify)
+		if err != nil {
+			return fmt.Errorf("%w: %w: default_verify: %w", ErrArgument, ErrInvalidArgument, err)
+		}
+
+		_, err = time.ParseDuration(*args.indexTTL)
+		if err != nil {
+			return fmt.Errorf("%w: %w: index_ttl: %w", ErrArgument, ErrInvalidArgument, err)
+		}
+
 		return nil
 	}
 
@@ -114,6 +138,7 @@ func NewChartCmd(logCfg *log.Config) *cobra.Command {
 	cmd.AddCommand(NewChartUpdateCmd(args))
 	cmd.AddCommand(NewChartSetCmd(args))
 	cmd.AddCommand(NewChartRepoCmd(args))
+	cmd.AddCommand(NewChartCacheCmd())
 
 	return cmd
 }
@@ -189,7 +214,7 @@ func NewChartAddCmd(args *ChartArgs) *cobra.Command {
 
 	cmd.Flags().StringVarP(chart, "chart", "c", "", "Helm chart name (required)")
 	cmd.Flags().StringVarP(repoURL, "repo_url", "r", "", "URL of the Helm chart repository (required)")
-	cmd.Flags().StringVarP(targetRevision, "target_revision", "t", "", "Semver tag for the chart's version")
+	cmd.Flags().StringVarP(targetRevision, "target_revision", "t", "", "Semver tag or constraint for the chart's version")
 	cmd.Flags().StringVar(schemaGenerator, "schema_generator", "", "Chart schema generator")
 	cmd.Flags().StringVar(schemaValidator, "schema_validator", "", "Chart schema validator")
 	cmd.Flags().StringVar(schemaPath, "schema_path", "", "Chart schema path")
@@ -280,9 +305,14 @@ func NewChartRepoAddCmd(args *ChartArgs) *cobra.Command {
 	url := new(string)
 	usernameEnv := new(string)
 	passwordEnv := new(string)
+	usernameFile := new(string)
+	passwordFile := new(string)
+	tokenFile := new(string)
 	caPath := new(string)
 	tlsClientCertDataPath := new(string)
 	tlsClientCertKeyPath := new(string)
+	verify := new(string)
+	keyring := new(string)
 	insecureSkipVerify := new(bool)
 	passCredentials := new(bool)
 
@@ -290,6 +320,11 @@ func NewChartRepoAddCmd(args *ChartArgs) *cobra.Command {
 		Use:   "add",
 		Short: "Add a new chart repository",
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			verifyMode, err := helmrepo.GetVerifyMode(*verify)
+			if err != nil {
+				return fmt.Errorf("%w: %w: verify: %w", ErrArgument, ErrInvalidArgument, err)
+			}
+
 			cc, closer, err := newChartCommander(cmd.OutOrStdout(), args)
 			if err != nil {
 				return fmt.Errorf("%w: %w", ErrChartCommand, err)
@@ -301,9 +336,14 @@ func NewChartRepoAddCmd(args *ChartArgs) *cobra.Command {
 				URL:                   *url,
 				UsernameEnv:           *usernameEnv,
 				PasswordEnv:           *passwordEnv,
+				UsernameFile:          *usernameFile,
+				PasswordFile:          *passwordFile,
+				TokenFile:             *tokenFile,
 				CAPath:                *caPath,
 				TLSClientCertDataPath: *tlsClientCertDataPath,
 				TLSClientCertKeyPath:  *tlsClientCertKeyPath,
+				Verify:                verifyMode,
+				Keyring:               *keyring,
 				InsecureSkipVerify:    *insecureSkipVerify,
 				PassCredentials:       *passCredentials,
 			}
@@ -321,9 +361,14 @@ func NewChartRepoAddCmd(args *ChartArgs) *cobra.Command {
 	cmd.Flags().StringVarP(url, "url", "u", "", "URL of the Helm chart repository (required)")
 	cmd.Flags().StringVarP(usernameEnv, "username_env", "U", "", "Basic authentication username environment variable")
 	cmd.Flags().StringVarP(passwordEnv, "password_env", "P", "", "Basic authentication password environment variable")
+	cmd.Flags().StringVar(usernameFile, "username_file", "", "Basic authentication username file path")
+	cmd.Flags().StringVar(passwordFile, "password_file", "", "Basic authentication password file path")
+	cmd.Flags().StringVar(tokenFile, "token_file", "", "Access token file path, used as the basic authentication password")
 	cmd.Flags().StringVar(caPath, "ca_path", "", "CA file path")
 	cmd.Flags().StringVar(tlsClientCertDataPath, "tls_client_cert_data_path", "", "TLS client certificate data path")
 	cmd.Flags().StringVar(tlsClientCertKeyPath, "tls_client_cert_key_path", "", "TLS client certificate key path")
+	cmd.Flags().StringVar(verify, "verify", "", "Chart provenance verification mode (NEVER, IF_POSSIBLE, ALWAYS)")
+	cmd.Flags().StringVar(keyring, "keyring", "", "Keyring file path used to verify chart provenance")
 	cmd.Flags().BoolVar(insecureSkipVerify, "insecure_skip_verify", false, "Skip SSL certificate verification")
 	cmd.Flags().BoolVar(passCredentials, "pass_credentials", false, "Pass credentials to the Helm chart repository")
 
@@ -333,37 +378,65 @@ func NewChartRepoAddCmd(args *ChartArgs) *cobra.Command {
 	return cmd
 }
 
+// newChartCommander returns the [charttui.ChartCommander] for args, and an
+// [io.Closer] which releases any resources it holds, such as a Helm client
+// created for non-default options and the TTY used for input.
+//
 //nolint:ireturn // Multiple concrete types.
 func newChartCommander(w io.Writer, args *ChartArgs) (charttui.ChartCommander, io.Closer, error) {
-	cc, err := chartcmd.NewKCLPackage(args.GetPath(), helm.DefaultClient,
+	var closer closers
+
+	client := helm.DefaultClient
+	if args.GetVerify() != helmrepo.VerifyModeDefault || args.GetIndexTTL() != helm.DefaultIndexCacheTTL {
+		var err error
+
+		client, err = helm.NewClient(
+			paths.NewStaticTempPaths(filepath.Join(os.TempDir(), "charts"), paths.NewBase64PathEncoder()),
+			os.Getenv("ARGOCD_APP_PROJECT_NAME"),
+			helm.WithVerify(args.GetVerify(), args.GetKeyring()),
+			helm.WithIndexCache(helm.NewIndexCache(filepath.Join(os.TempDir(), "indexes"), args.GetIndexTTL())),
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrChartCommand, err)
+		}
+
+		closer = append(closer, client)
+	}
+
+	cc, err := chartcmd.NewKCLPackage(args.GetPath(), client,
 		chartcmd.WithTimeout(args.GetTimeout()),
 		chartcmd.WithVendor(args.GetVendor()),
 		chartcmd.WithMaxExtractSize(args.GetMaxExtractSize()),
 	)
 	if err != nil {
+		_ = closer.Close()
+
 		return nil, nil, fmt.Errorf("%w: %w", ErrChartCommand, err)
 	}
 
 	if args.GetQuiet() || !isatty.IsTerminal(os.Stdout.Fd()) {
-		return cc, helm.NewNopCloser(), nil
+		return cc, closer, nil
 	}
 
 	lvl, err := args.logCfg.ParsedLevel()
 	if err != nil {
+		_ = closer.Close()
+
 		// Should not be possible due to root's PersistentPreRunE.
 		return nil, nil, fmt.Errorf("%w: %w", ErrArgument, err)
 	}
 
 	var tuiOpts []charttui.ChartTUIOption
 
-	closer := helm.NewNopCloser()
 	if !isatty.IsTerminal(os.Stdin.Fd()) {
 		ttyIn, _, err := tea.OpenTTY()
 		if err != nil {
+			_ = closer.Close()
+
 			return nil, nil, fmt.Errorf("open tty for input: %w", err)
 		}
 
-		closer = ttyIn
+		closer = append(closer, ttyIn)
 
 		tuiOpts = append(tuiOpts, charttui.WithProgramOptions(tea.WithInput(ttyIn)))
 	}
@@ -371,11 +444,27 @@ func newChartCommander(w io.Writer, args *ChartArgs) (charttui.ChartCommander, i
 	return charttui.NewChartTUI(w, lvl, cc, tuiOpts...), closer, nil
 }
 
+// closers is an [io.Closer] which closes each of its elements.
+type closers []io.Closer
+
+// Close closes each element in order, returning all errors.
+func (cs closers) Close() error {
+	errs := make([]error, 0, len(cs))
+	for _, c := range cs {
+		errs = append(errs, c.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
 // ChartArgs holds the arguments for the chart command.
 // Create instances with [NewChartArgs].
 type ChartArgs struct {
 	path           *string
 	maxExtractSize *string
+	verify         *string
+	keyring        *string
+	indexTTL       *string
 	timeout        *time.Duration
 	quiet          *bool
 	vendor         *bool
@@ -387,6 +476,9 @@ func NewChartArgs(logCfg *log.Config) *ChartArgs {
 	return &ChartArgs{
 		path:           new(string),
 		maxExtractSize: new(string),
+		verify:         new(string),
+		keyring:        new(string),
+		indexTTL:       new(string),
 		timeout:        new(time.Duration),
 		quiet:          new(bool),
 		vendor:         new(bool),
@@ -407,6 +499,28 @@ func (a *ChartArgs) GetMaxExtractSize() *resource.Quantity {
 	return &size
 }
 
+func (a *ChartArgs) GetVerify() helmrepo.VerifyMode {
+	mode, err := helmrepo.GetVerifyMode(*a.verify)
+	if err != nil {
+		panic(err)
+	}
+
+	return mode
+}
+
+func (a *ChartArgs) GetKeyring() string {
+	return *a.keyring
+}
+
+func (a *ChartArgs) GetIndexTTL() time.Duration {
+	ttl, err := time.ParseDuration(*a.indexTTL)
+	if err != nil {
+		panic(err)
+	}
+
+	return ttl
+}
+
 func (a *ChartArgs) GetTimeout() time.Duration {
 	return *a.timeout
 }
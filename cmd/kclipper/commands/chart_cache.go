@@ -0,0 +1,327 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+const (
+	chartCacheExample = `  # List cached charts
+  kcl chart cache list
+
+  # Remove cached charts which have not been used in a week
+  kcl chart cache clean --older_than 168h
+
+  # Remove least recently used charts until the cache fits in 5Gi
+  kcl chart cache clean --max_size 5Gi
+
+  # Check cached podinfo charts for corrupt archives
+  kcl chart cache verify --chart podinfo -o json
+`
+
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+var (
+	// ErrChartCache indicates a chart cache command did not succeed.
+	ErrChartCache = errors.New("chart cache")
+
+	// ErrChartCacheVerify indicates that corrupt charts were found in the cache.
+	ErrChartCacheVerify = errors.New("chart cache verify")
+)
+
+// ChartCacheArgs holds the arguments for the chart cache commands.
+// Create instances with [NewChartCacheArgs].
+type ChartCacheArgs struct {
+	dir       *string
+	chart     *string
+	repoURL   *string
+	project   *string
+	output    *string
+	olderThan *time.Duration
+}
+
+// NewChartCacheArgs creates a new [ChartCacheArgs].
+func NewChartCacheArgs() *ChartCacheArgs {
+	return &ChartCacheArgs{
+		dir:       new(string),
+		chart:     new(string),
+		repoURL:   new(string),
+		project:   new(string),
+		output:    new(string),
+		olderThan: new(time.Duration),
+	}
+}
+
+// GetFilter returns the [helm.CacheFilter] selected by the arguments.
+func (a *ChartCacheArgs) GetFilter() helm.CacheFilter {
+	f := helm.CacheFilter{
+		Chart:   *a.chart,
+		RepoURL: *a.repoURL,
+		Project: *a.project,
+	}
+
+	if *a.olderThan > 0 {
+		f.AccessedBefore = time.Now().Add(-*a.olderThan)
+	}
+
+	return f
+}
+
+// NewChartCacheCmd returns the chart cache [*cobra.Command].
+func NewChartCacheCmd() *cobra.Command {
+	args := NewChartCacheArgs()
+
+	cmd := &cobra.Command{
+		Use:     "cache",
+		Short:   "Helm chart cache management",
+		Example: chartCacheExample,
+	}
+
+	cmd.PersistentFlags().StringVar(args.dir, "cache_dir", filepath.Join(os.TempDir(), "charts"),
+		"Chart cache directory")
+	cmd.PersistentFlags().StringVarP(args.chart, "chart", "c", "", "Only include charts with this name")
+	cmd.PersistentFlags().StringVarP(args.repoURL, "repo_url", "r", "", "Only include charts from this repository URL")
+	cmd.PersistentFlags().StringVar(args.project, "project", "", "Only include charts pulled for this project")
+	cmd.PersistentFlags().DurationVar(args.olderThan, "older_than", 0,
+		"Only include charts which have not been used for this long")
+	cmd.PersistentFlags().StringVarP(args.output, "output", "o", outputTable, "Output format (table, json)")
+
+	must(cmd.MarkPersistentFlagDirname("cache_dir"))
+
+	cmd.AddCommand(NewChartCacheListCmd(args))
+	cmd.AddCommand(NewChartCacheCleanCmd(args))
+	cmd.AddCommand(NewChartCacheVerifyCmd(args))
+
+	return cmd
+}
+
+// NewChartCacheListCmd returns the chart cache list [*cobra.Command].
+func NewChartCacheListCmd(args *ChartCacheArgs) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List cached charts",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := newChartCacheClient(args)
+			if err != nil {
+				return err
+			}
+
+			defer client.Close() //nolint:errcheck // Best-effort close.
+
+			entries, err := client.CacheEntries(args.GetFilter())
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrChartCache, err)
+			}
+
+			return writeCacheEntries(cmd.OutOrStdout(), *args.output, entries, nil)
+		},
+	}
+}
+
+// NewChartCacheCleanCmd returns the chart cache clean [*cobra.Command].
+func NewChartCacheCleanCmd(args *ChartCacheArgs) *cobra.Command {
+	maxSize := new(string)
+
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove cached charts",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if *maxSize == "" {
+				client, err := newChartCacheClient(args)
+				if err != nil {
+					return err
+				}
+
+				defer client.Close() //nolint:errcheck // Best-effort close.
+
+				entries, err := client.RemoveCacheEntries(args.GetFilter())
+				if err != nil {
+					return fmt.Errorf("%w: %w", ErrChartCache, err)
+				}
+
+				return writeCacheEntries(cmd.OutOrStdout(), *args.output, entries, nil)
+			}
+
+			if *args.chart != "" || *args.repoURL != "" || *args.project != "" {
+				return fmt.Errorf("%w: %w: max_size cannot be combined with chart, repo_url or project",
+					ErrArgument, ErrInvalidArgument)
+			}
+
+			size, err := resource.ParseQuantity(*maxSize)
+			if err != nil {
+				return fmt.Errorf("%w: %w: max_size: %w", ErrArgument, ErrInvalidArgument, err)
+			}
+
+			client, err := newChartCacheClient(args, helm.WithCacheLimits(size.Value(), *args.olderThan))
+			if err != nil {
+				return err
+			}
+
+			defer client.Close() //nolint:errcheck // Best-effort close.
+
+			entries, err := client.PruneCache(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrChartCache, err)
+			}
+
+			return writeCacheEntries(cmd.OutOrStdout(), *args.output, entries, nil)
+		},
+	}
+
+	cmd.Flags().StringVar(maxSize, "max_size", "",
+		"Remove least recently used charts until the cache is no larger than this size")
+
+	return cmd
+}
+
+// NewChartCacheVerifyCmd returns the chart cache verify [*cobra.Command].
+func NewChartCacheVerifyCmd(args *ChartCacheArgs) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Check cached charts for corrupt archives",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := newChartCacheClient(args)
+			if err != nil {
+				return err
+			}
+
+			defer client.Close() //nolint:errcheck // Best-effort close.
+
+			entries, err := client.CacheEntries(args.GetFilter())
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrChartCache, err)
+			}
+
+			errs := make([]error, len(entries))
+			for i, e := range entries {
+				errs[i] = client.VerifyCacheEntry(e)
+			}
+
+			err = writeCacheEntries(cmd.OutOrStdout(), *args.output, entries, errs)
+			if err != nil {
+				return err
+			}
+
+			corrupt := 0
+
+			for _, err := range errs {
+				if err != nil {
+					corrupt++
+				}
+			}
+
+			if corrupt > 0 {
+				return fmt.Errorf("%w: %d of %d cached charts are corrupt", ErrChartCacheVerify, corrupt, len(entries))
+			}
+
+			return nil
+		},
+	}
+}
+
+func newChartCacheClient(args *ChartCacheArgs, opts ...helm.ClientOption) (*helm.Client, error) {
+	if *args.output != outputTable && *args.output != outputJSON {
+		return nil, fmt.Errorf("%w: %w: output: %q", ErrArgument, ErrInvalidArgument, *args.output)
+	}
+
+	client, err := helm.NewClient(
+		paths.NewStaticTempPaths(*args.dir, paths.NewBase64PathEncoder()),
+		os.Getenv("ARGOCD_APP_PROJECT_NAME"),
+		opts...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrChartCommand, err)
+	}
+
+	return client, nil
+}
+
+type cacheEntryOutput struct {
+	*helm.CacheEntry
+
+	Error string `json:"error,omitempty"`
+}
+
+// writeCacheEntries writes the entries in the given output format. If errs is
+// not nil, it holds the verification result of each entry.
+func writeCacheEntries(w io.Writer, output string, entries []*helm.CacheEntry, errs []error) error {
+	if output == outputJSON {
+		out := make([]cacheEntryOutput, len(entries))
+		for i, e := range entries {
+			out[i].CacheEntry = e
+			if errs != nil && errs[i] != nil {
+				out[i].Error = errs[i].Error()
+			}
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		err := enc.Encode(out)
+		if err != nil {
+			return fmt.Errorf("%w: write json: %w", ErrChartCache, err)
+		}
+
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := "CHART\tREPO\tVERSION\tPROJECT\tSIZE\tLAST ACCESS"
+	if errs != nil {
+		header += "\tSTATUS"
+	}
+
+	fmt.Fprintln(tw, header)
+
+	for i, e := range entries {
+		version := e.Version
+		if e.Digest != "" {
+			version = strings.TrimPrefix(version+"@"+e.Digest, "@")
+		}
+
+		chart := e.Chart
+		if e.GitCheckout {
+			chart = "(git checkout)"
+		}
+
+		line := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s",
+			chart, e.RepoURL, version, e.Project,
+			resource.NewQuantity(e.Size, resource.BinarySI).String(),
+			e.LastAccess.Format(time.RFC3339),
+		)
+
+		if errs != nil {
+			status := "ok"
+			if errs[i] != nil {
+				status = errs[i].Error()
+			}
+
+			line += "\t" + status
+		}
+
+		fmt.Fprintln(tw, line)
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return fmt.Errorf("%w: write table: %w", ErrChartCache, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/cmd/kclipper/commands"
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+func TestChartCacheCmd(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := t.TempDir()
+
+	chartPath, err := paths.NewStaticTempPaths(cacheDir, paths.NewBase64PathEncoder()).GetPath(
+		`{"chart":"podinfo","project":"test","url":"https://example.com","version":"6.7.0"}`,
+	)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(chartPath, []byte("not a chart"), 0o600))
+
+	execute := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+
+		rootCmdMu.Lock()
+		defer rootCmdMu.Unlock()
+
+		rootCmd := commands.NewRootCmd("test_chart_cache", "", "")
+		stdout := &bytes.Buffer{}
+
+		rootCmd.SetArgs(append([]string{"chart", "cache", "--cache_dir", cacheDir, "-o", "json"}, args...))
+		rootCmd.SetOut(stdout)
+		rootCmd.SetErr(&bytes.Buffer{})
+
+		err := rootCmd.Execute()
+
+		return stdout.String(), err
+	}
+
+	type entry struct {
+		Chart   string `json:"chart"`
+		RepoURL string `json:"repoURL"`
+		Version string `json:"version"`
+		Project string `json:"project"`
+		Error   string `json:"error"`
+	}
+
+	out, err := execute(t, "list")
+	require.NoError(t, err)
+
+	entries := []entry{}
+	require.NoError(t, json.Unmarshal([]byte(out), &entries))
+	assert.Equal(t, []entry{{
+		Chart:   "podinfo",
+		RepoURL: "https://example.com",
+		Version: "6.7.0",
+		Project: "test",
+	}}, entries)
+
+	out, err = execute(t, "verify")
+	require.ErrorIs(t, err, commands.ErrChartCacheVerify)
+	require.NoError(t, json.Unmarshal([]byte(out), &entries))
+	require.Len(t, entries, 1)
+	assert.NotEmpty(t, entries[0].Error)
+
+	out, err = execute(t, "clean", "--chart", "other")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(out), &entries))
+	assert.Empty(t, entries)
+	assert.FileExists(t, chartPath)
+
+	_, err = execute(t, "clean", "--max_size", "1", "--chart", "podinfo")
+	require.ErrorIs(t, err, commands.ErrInvalidArgument)
+
+	out, err = execute(t, "clean", "--max_size", "1")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(out), &entries))
+	require.Len(t, entries, 1)
+	assert.NoFileExists(t, chartPath)
+
+	require.NoError(t, os.WriteFile(chartPath, []byte("not a chart"), 0o600))
+
+	_, err = execute(t, "clean")
+	require.NoError(t, err)
+	assert.NoFileExists(t, chartPath)
+
+	_, err = execute(t, "list", "-o", "yaml")
+	require.ErrorIs(t, err, commands.ErrInvalidArgument)
+}
@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"context"
+	"log/slog"
 	"os"
 	"strings"
 
@@ -8,8 +10,9 @@ import (
 	helmplugin "github.com/macropower/kclipper/pkg/kclplugin/helm"
 )
 
-func RegisterEnabledPlugins() {
+func RegisterEnabledPlugins(ctx context.Context) {
 	if !envTrue("KCLIPPER_HELM_PLUGIN_DISABLED") {
+		helmplugin.SetContext(ctx)
 		helmplugin.Register()
 	}
 
@@ -18,6 +21,15 @@ func RegisterEnabledPlugins() {
 	}
 }
 
+// CloseEnabledPlugins releases the resources held by plugins registered with
+// [RegisterEnabledPlugins]. It should be called before the process exits.
+func CloseEnabledPlugins() {
+	err := helmplugin.Close()
+	if err != nil {
+		slog.Warn("close helm plugin", slog.Any("err", err))
+	}
+}
+
 func envTrue(key string) bool {
 	return strings.ToLower(os.Getenv(key)) == "true"
 }
@@ -10,6 +10,8 @@ import (
 	"go.jacobcolvin.com/x/cobras/profile"
 
 	kclcmd "kcl-lang.io/cli/cmd/kcl/commands"
+
+	"github.com/macropower/kclipper/pkg/offline"
 )
 
 // ErrLogHandler indicates an error occurred while creating a log handler.
@@ -57,6 +59,9 @@ func NewRootCmd(name, shortDesc, longDesc string) *cobra.Command {
 
 	profiler := profileCfg.NewProfiler()
 
+	offlineMode := cmd.PersistentFlags().Bool("offline", offline.Enabled(),
+		"Refuse network access and only use cached charts (env: "+offline.EnvVar+")")
+
 	cmd.PersistentPreRunE = func(cc *cobra.Command, _ []string) error {
 		err := profiler.Start()
 		if err != nil {
@@ -70,7 +75,9 @@ func NewRootCmd(name, shortDesc, longDesc string) *cobra.Command {
 
 		slog.SetDefault(slog.New(h))
 
-		slog.Debug("ready to go")
+		offline.SetEnabled(*offlineMode)
+
+		slog.Debug("ready to go", slog.Bool("offline", *offlineMode))
 
 		return nil
 	}
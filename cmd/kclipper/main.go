@@ -6,7 +6,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"charm.land/fang/v2"
 	"github.com/spf13/cobra"
@@ -38,39 +40,58 @@ The KCL website: https://kcl-lang.io
 )
 
 func main() {
-	commands.RegisterEnabledPlugins()
+	// Cancel in-flight work (e.g. chart pulls made by KCL plugins) on interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	// Restore the default signal handling after the first signal, so that a
+	// second one kills work which does not observe the context.
+	context.AfterFunc(ctx, stop)
+
+	code := run(ctx)
+
+	stop()
+	os.Exit(code)
+}
+
+// run executes the root command and returns the process exit code.
+func run(ctx context.Context) int {
+	commands.RegisterEnabledPlugins(ctx)
+	defer commands.CloseEnabledPlugins()
 
 	cmd := commands.NewRootCmd(cmdName, shortDesc, longDesc)
 
-	ok, err := bootstrapCmdPlugin(cmd, plugin.NewDefaultPluginHandler([]string{cmdName}))
+	ok, err := bootstrapCmdPlugin(ctx, cmd, plugin.NewDefaultPluginHandler([]string{cmdName}))
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+
+		return 1
 	}
 
 	if ok {
-		return
+		return 0
 	}
 
 	// Errors are ignored because the "charm" theme is always available;
 	// even if it were not, a nil styles value produces valid defaults.
 	styles, _ := theme.Styles("charm")
 
-	err = fang.Execute(context.Background(), cmd,
+	err = fang.Execute(ctx, cmd,
 		fang.WithErrorHandler(fangs.ErrorHandler),
 		fang.WithColorSchemeFunc(fangs.ColorSchemeFunc(styles)),
 	)
 	if err != nil {
-		os.Exit(1)
+		return 1
 	}
+
+	return 0
 }
 
 // executeRunCmd executes the run command for the root command.
-func executeRunCmd(args []string) error {
+func executeRunCmd(ctx context.Context, args []string) error {
 	cmd := kclcmd.NewRunCmd()
 	cmd.SetArgs(args)
 
-	err := cmd.Execute()
+	err := cmd.ExecuteContext(ctx)
 	if err != nil {
 		return fmt.Errorf("execute run command: %w", err)
 	}
@@ -82,7 +103,7 @@ func isHelpOrVersionFlag(flag string) bool {
 	return flag == "-h" || flag == "--help" || flag == "-v" || flag == "--version"
 }
 
-func bootstrapCmdPlugin(cmd *cobra.Command, pluginHandler plugin.PluginHandler) (bool, error) {
+func bootstrapCmdPlugin(ctx context.Context, cmd *cobra.Command, pluginHandler plugin.PluginHandler) (bool, error) {
 	if pluginHandler == nil {
 		return false, nil
 	}
@@ -97,7 +118,7 @@ func bootstrapCmdPlugin(cmd *cobra.Command, pluginHandler plugin.PluginHandler)
 	// the specified command does not already exist.
 	// Flags cannot be placed before plugin name.
 	if strings.HasPrefix(cmdPathPieces[0], "-") && !isHelpOrVersionFlag(cmdPathPieces[0]) {
-		return true, executeRunCmd(cmdPathPieces)
+		return true, executeRunCmd(ctx, cmdPathPieces)
 	}
 
 	foundCmd, _, err := cmd.Find(cmdPathPieces)
@@ -138,7 +159,7 @@ func bootstrapCmdPlugin(cmd *cobra.Command, pluginHandler plugin.PluginHandler)
 				return false, fmt.Errorf("handle plugin command: %w", err)
 			}
 
-			return true, executeRunCmd(cmdPathPieces)
+			return true, executeRunCmd(ctx, cmdPathPieces)
 		}
 	}
 
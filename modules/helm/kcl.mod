@@ -5,4 +5,3 @@ version = "0.0.1"
 
 [dependencies]
 k8s = "1.31.2"
-json_merge_patch = "0.1.1"
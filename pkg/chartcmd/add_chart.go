@@ -27,7 +27,8 @@ const initialChartContents = `import helm
 charts: helm.Charts = {}
 `
 
-// AddChart adds a new chart to the chart package.
+// AddChart adds a new chart to the chart package, and records the pulled chart
+// in charts.lock.
 func (c *KCLPackage) AddChart(key string, chart *kclchart.ChartConfig) error {
 	err := chart.Validate()
 	if err != nil {
@@ -58,7 +59,19 @@ func (c *KCLPackage) AddChart(key string, chart *kclchart.ChartConfig) error {
 	}
 	defer helmChart.Dispose()
 
-	err = generateAndWriteChartKCL(&kclchart.Chart{ChartBase: chart.ChartBase}, chartDir, logger)
+	// Pin version constraints to the resolved version, so that the generated
+	// chart.k keeps rendering the same chart until the next update.
+	chartBase := chart.ChartBase
+	if version := helmChart.Version(); version != "" && helm.IsVersionConstraint(chart.TargetRevision) {
+		logger.Info("pin resolved chart version",
+			slog.String("constraint", chart.TargetRevision),
+			slog.String("version", version),
+		)
+
+		chartBase.TargetRevision = version
+	}
+
+	err = generateAndWriteChartKCL(&kclchart.Chart{ChartBase: chartBase}, chartDir, logger)
 	if err != nil {
 		return err
 	}
@@ -87,6 +100,16 @@ func (c *KCLPackage) AddChart(key string, chart *kclchart.ChartConfig) error {
 		}
 	}
 
+	locked, err := lockChart(chart, helmChart)
+	if err != nil {
+		return err
+	}
+
+	err = c.updateLockFile(key, locked, logger)
+	if err != nil {
+		return err
+	}
+
 	err = c.updateChartsFile(key, chart, logger)
 	if err != nil {
 		return err
@@ -130,7 +153,7 @@ func (c *KCLPackage) setupHelmChart(chart *kclchart.ChartConfig, logger *slog.Lo
 			slog.String("url", repo.URL),
 		)
 
-		hr, err := repo.GetHelmRepo()
+		hr, err := repo.GetHelmRepo(c.pkgPath, c.repoRoot)
 		if err != nil {
 			return nil, fmt.Errorf("get helm repository: %w", err)
 		}
@@ -144,12 +167,23 @@ func (c *KCLPackage) setupHelmChart(chart *kclchart.ChartConfig, logger *slog.Lo
 	// Prepare chart values.
 	chartValues := map[string]any{}
 	if chart.Values != nil {
-		var ok bool
-
-		chartValues, ok = chart.Values.(map[string]any)
+		values, ok := chart.Values.(map[string]any)
 		if !ok {
 			return nil, fmt.Errorf("invalid values type: %T", chart.Values)
 		}
+
+		chartValues = values
+	}
+
+	overrides := &helm.SetValues{
+		Set:       chart.Set,
+		SetString: chart.SetString,
+		SetFile:   chart.SetFile,
+	}
+
+	err := overrides.ReadFiles(c.pkgPath, c.repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("read value override files: %w", err)
 	}
 
 	// Load helm chart.
@@ -161,7 +195,10 @@ func (c *KCLPackage) setupHelmChart(chart *kclchart.ChartConfig, logger *slog.Lo
 		RepoURL:         chart.RepoURL,
 		SkipCRDs:        chart.SkipCRDs,
 		PassCredentials: chart.PassCredentials,
+		KubeVersion:     chart.KubeVersion,
+		APIVersions:     chart.APIVersions,
 		ValuesObject:    chartValues,
+		SetValues:       overrides,
 		// KCL validates values against the generated schema, so Helm-side
 		// validation (which can load remote JSON Schema refs) is redundant.
 		SkipSchemaValidation: true,
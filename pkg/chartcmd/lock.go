@@ -0,0 +1,119 @@
+package chartcmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/macropower/kclipper/pkg/chartlock"
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/kclmodule/kclchart"
+)
+
+// lockChart returns the [chartlock.Chart] recording the version and digest of
+// the pulled chart, and of each of its pulled dependencies.
+func lockChart(chart *kclchart.ChartConfig, helmChart *helm.ChartFiles) (*chartlock.Chart, error) {
+	deps, err := helmChart.Dependencies(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("get chart dependencies: %w", err)
+	}
+
+	dgst, err := helmChart.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("get chart digest: %w", err)
+	}
+
+	locked := &chartlock.Chart{
+		Chart:   chart.Chart,
+		RepoURL: chart.RepoURL,
+		Version: helmChart.Version(),
+		Digest:  dgst,
+	}
+
+	for _, dep := range deps {
+		depDigest, err := dep.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("get digest of dependency %q: %w", dep.Name(), err)
+		}
+
+		locked.Dependencies = append(locked.Dependencies, &chartlock.Dependency{
+			Name:       dep.Name(),
+			Repository: dep.RepoURL(),
+			Version:    dep.Version(),
+			Digest:     depDigest,
+		})
+	}
+
+	slices.SortFunc(locked.Dependencies, func(a, b *chartlock.Dependency) int {
+		return cmp.Or(
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Repository, b.Repository),
+			cmp.Compare(a.Version, b.Version),
+		)
+	})
+
+	// The same dependency may be pulled by more than one chart.
+	locked.Dependencies = slices.CompactFunc(locked.Dependencies, func(a, b *chartlock.Dependency) bool {
+		return *a == *b
+	})
+
+	return locked, nil
+}
+
+// updateLockFile sets the entry for key in the charts.lock file.
+func (c *KCLPackage) updateLockFile(key string, locked *chartlock.Chart, logger *slog.Logger) error {
+	lockFile := filepath.Join(c.BasePath, chartlock.FileName)
+
+	logger.Info("updating charts.lock",
+		slog.String("path", lockFile),
+		slog.String("version", locked.Version),
+	)
+
+	return c.modifyLockFile(lockFile, func(lock *chartlock.Lock) {
+		lock.Charts[key] = locked
+	})
+}
+
+// pruneLockFile removes entries from the charts.lock file whose keys are not
+// in keys.
+func (c *KCLPackage) pruneLockFile(keys []string, logger *slog.Logger) error {
+	lockFile := filepath.Join(c.BasePath, chartlock.FileName)
+
+	logger.Debug("pruning charts.lock", slog.String("path", lockFile))
+
+	return c.modifyLockFile(lockFile, func(lock *chartlock.Lock) {
+		for key := range lock.Charts {
+			if !slices.Contains(keys, key) {
+				delete(lock.Charts, key)
+			}
+		}
+	})
+}
+
+// modifyLockFile reads the lock file, applies modify, and writes it back. A
+// new lock is created if the file does not exist.
+func (c *KCLPackage) modifyLockFile(lockFile string, modify func(*chartlock.Lock)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lock, err := chartlock.ReadFile(lockFile)
+	if errors.Is(err, os.ErrNotExist) {
+		lock = chartlock.New()
+	} else if err != nil {
+		return fmt.Errorf("read charts.lock: %w", err)
+	}
+
+	modify(lock)
+
+	err = lock.WriteFile(lockFile)
+	if err != nil {
+		return fmt.Errorf("write charts.lock: %w", err)
+	}
+
+	return nil
+}
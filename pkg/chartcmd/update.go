@@ -23,7 +23,8 @@ var (
 )
 
 // Update loads the chart configurations defined in charts.k and calls Add to
-// generate all required chart packages.
+// generate all required chart packages. The charts.lock file is updated to
+// match.
 func (c *KCLPackage) Update(charts ...string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
 	defer cancel()
@@ -161,6 +162,15 @@ func (c *KCLPackage) Update(charts ...string) error {
 		return merr
 	}
 
+	// Charts which were removed from charts.k are only pruned from charts.lock
+	// when all charts were updated.
+	if len(charts) == 0 {
+		err := c.pruneLockFile(chartData.GetSortedKeys(), logger)
+		if err != nil {
+			return err
+		}
+	}
+
 	logger.Info("update complete")
 
 	return nil
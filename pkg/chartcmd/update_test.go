@@ -5,9 +5,11 @@ import (
 	"path"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/macropower/kclipper/pkg/chartcmd"
+	"github.com/macropower/kclipper/pkg/chartlock"
 	"github.com/macropower/kclipper/pkg/helmtest"
 	"github.com/macropower/kclipper/pkg/kclmodule/kclchart"
 )
@@ -40,4 +42,11 @@ func TestHelmChartUpdate(t *testing.T) {
 
 	err = chartPkg.Update()
 	require.NoError(t, err)
+
+	lock, err := chartlock.ReadFile(path.Join(chartPath, chartlock.FileName))
+	require.NoError(t, err)
+	require.Contains(t, lock.Charts, "podinfo")
+	assert.Equal(t, "6.7.1", lock.Charts["podinfo"].Version)
+	assert.Equal(t, "https://stefanprodan.github.io/podinfo", lock.Charts["podinfo"].RepoURL)
+	assert.NotEmpty(t, lock.Charts["podinfo"].Digest)
 }
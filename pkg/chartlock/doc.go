@@ -0,0 +1,9 @@
+// Package chartlock provides functionality for reading and writing charts.lock
+// files.
+//
+// A charts.lock file is written next to charts.k by the `kcl chart` command. It
+// records the exact version, repository URL, and archive digest of each chart,
+// along with the versions of any subcharts that were pulled. When rendering,
+// the lock is used to pin version constraints and to verify pulled charts, so
+// that the same charts are rendered everywhere.
+package chartlock
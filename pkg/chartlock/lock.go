@@ -0,0 +1,189 @@
+package chartlock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/Masterminds/semver/v3"
+	"sigs.k8s.io/yaml"
+)
+
+// FileName is the name of the lock file, which is written next to charts.k.
+const FileName = "charts.lock"
+
+// ErrInvalidLock indicates that a lock file could not be parsed.
+var ErrInvalidLock = errors.New("invalid chart lock")
+
+// Lock is the contents of a charts.lock file. Create instances with [New] or
+// [ReadFile].
+type Lock struct {
+	// Charts maps chart keys in charts.k to their locked [Chart].
+	Charts map[string]*Chart `json:"charts"`
+}
+
+// Chart is a locked chart.
+type Chart struct {
+	// Chart is the name of the chart.
+	Chart string `json:"chart"`
+	// RepoURL is the repository URL, as written in charts.k.
+	RepoURL string `json:"repoURL"`
+	// Version is the exact version that was pulled.
+	Version string `json:"version,omitempty"`
+	// Digest is the digest of the chart archive. It is empty for local charts.
+	Digest string `json:"digest,omitempty"`
+	// Dependencies lists the subcharts that were pulled for the chart.
+	Dependencies []*Dependency `json:"dependencies,omitempty"`
+}
+
+// Dependency is a locked subchart.
+type Dependency struct {
+	// Name is the name of the subchart.
+	Name string `json:"name"`
+	// Repository is the repository URL, as written in the parent's Chart.yaml.
+	Repository string `json:"repository"`
+	// Version is the exact version that was pulled.
+	Version string `json:"version"`
+	// Digest is the digest of the subchart archive.
+	Digest string `json:"digest,omitempty"`
+}
+
+// New creates a new, empty [Lock].
+func New() *Lock {
+	return &Lock{Charts: map[string]*Chart{}}
+}
+
+// ReadFile reads the [Lock] at path. If the file does not exist, the returned
+// error wraps [os.ErrNotExist].
+func ReadFile(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	lock := New()
+
+	err = yaml.Unmarshal(data, lock)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidLock, path, err)
+	}
+
+	if lock.Charts == nil {
+		lock.Charts = map[string]*Chart{}
+	}
+
+	return lock, nil
+}
+
+// WriteFile writes the [Lock] to path. Chart keys are written in sorted order,
+// so that the file is stable between updates.
+func (l *Lock) WriteFile(path string) error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("marshal chart lock: %w", err)
+	}
+
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// LockedVersion returns the highest locked version of chart in repoURL which
+// satisfies constraint. Both charts and their dependencies are considered.
+func (l *Lock) LockedVersion(chart, repoURL, constraint string) (string, bool) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", false
+	}
+
+	var best *semver.Version
+
+	for _, e := range l.entries(chart, repoURL) {
+		v, err := semver.NewVersion(e.version)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+
+		if best == nil || v.GreaterThan(best) {
+			best = v
+		}
+	}
+
+	if best == nil {
+		return "", false
+	}
+
+	return best.Original(), true
+}
+
+// LockedVersions returns the sorted, distinct locked versions of chart in
+// repoURL. Both charts and their dependencies are considered.
+func (l *Lock) LockedVersions(chart, repoURL string) []string {
+	var versions []string
+
+	for _, e := range l.entries(chart, repoURL) {
+		if e.version != "" && !slices.Contains(versions, e.version) {
+			versions = append(versions, e.version)
+		}
+	}
+
+	slices.Sort(versions)
+
+	return versions
+}
+
+// LockedDigests returns the distinct locked archive digests of chart in
+// repoURL, sorted. Both charts and their dependencies are considered.
+func (l *Lock) LockedDigests(chart, repoURL string) []string {
+	var digests []string
+
+	for _, e := range l.entries(chart, repoURL) {
+		if e.digest != "" && !slices.Contains(digests, e.digest) {
+			digests = append(digests, e.digest)
+		}
+	}
+
+	slices.Sort(digests)
+
+	return digests
+}
+
+// LockedDigest returns the locked archive digest of chart in repoURL at the
+// given version. Both charts and their dependencies are considered.
+func (l *Lock) LockedDigest(chart, repoURL, version string) (string, bool) {
+	for _, e := range l.entries(chart, repoURL) {
+		if e.version == version && e.digest != "" {
+			return e.digest, true
+		}
+	}
+
+	return "", false
+}
+
+type entry struct {
+	version string
+	digest  string
+}
+
+// entries returns the locked versions and digests of chart in repoURL.
+func (l *Lock) entries(chart, repoURL string) []entry {
+	var entries []entry
+
+	for _, c := range l.Charts {
+		if c.Chart == chart && c.RepoURL == repoURL {
+			entries = append(entries, entry{version: c.Version, digest: c.Digest})
+		}
+
+		for _, d := range c.Dependencies {
+			if d.Name == chart && d.Repository == repoURL {
+				entries = append(entries, entry{version: d.Version, digest: d.Digest})
+			}
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,154 @@
+package chartlock_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/chartlock"
+)
+
+func newTestLock() *chartlock.Lock {
+	lock := chartlock.New()
+	lock.Charts["app"] = &chartlock.Chart{
+		Chart:   "app",
+		RepoURL: "https://charts.example.com",
+		Version: "1.2.3",
+		Digest:  "sha256:aaa",
+		Dependencies: []*chartlock.Dependency{
+			{
+				Name:       "redis",
+				Repository: "oci://registry.example.com/charts/redis",
+				Version:    "18.0.1",
+				Digest:     "sha256:bbb",
+			},
+		},
+	}
+	lock.Charts["app-next"] = &chartlock.Chart{
+		Chart:   "app",
+		RepoURL: "https://charts.example.com",
+		Version: "1.3.0",
+		Digest:  "sha256:ccc",
+	}
+
+	return lock
+}
+
+func TestLockFile(t *testing.T) {
+	t.Parallel()
+
+	lockFile := filepath.Join(t.TempDir(), chartlock.FileName)
+
+	_, err := chartlock.ReadFile(lockFile)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	want := newTestLock()
+
+	err = want.WriteFile(lockFile)
+	require.NoError(t, err)
+
+	got, err := chartlock.ReadFile(lockFile)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	err = os.WriteFile(lockFile, []byte("charts: ["), 0o600)
+	require.NoError(t, err)
+
+	_, err = chartlock.ReadFile(lockFile)
+	require.ErrorIs(t, err, chartlock.ErrInvalidLock)
+}
+
+func TestLockedVersion(t *testing.T) {
+	t.Parallel()
+
+	lock := newTestLock()
+
+	tcs := map[string]struct {
+		chart      string
+		repoURL    string
+		constraint string
+		want       string
+		wantOK     bool
+	}{
+		"highest match": {
+			chart:      "app",
+			repoURL:    "https://charts.example.com",
+			constraint: "^1.2.0",
+			want:       "1.3.0",
+			wantOK:     true,
+		},
+		"tilde match": {
+			chart:      "app",
+			repoURL:    "https://charts.example.com",
+			constraint: "~1.2.0",
+			want:       "1.2.3",
+			wantOK:     true,
+		},
+		"dependency": {
+			chart:      "redis",
+			repoURL:    "oci://registry.example.com/charts/redis",
+			constraint: "18.x",
+			want:       "18.0.1",
+			wantOK:     true,
+		},
+		"no match": {
+			chart:      "app",
+			repoURL:    "https://charts.example.com",
+			constraint: "~2.0.0",
+		},
+		"other repo": {
+			chart:      "app",
+			repoURL:    "https://other.example.com",
+			constraint: "^1.2.0",
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := lock.LockedVersion(tc.chart, tc.repoURL, tc.constraint)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestLockedVersions(t *testing.T) {
+	t.Parallel()
+
+	lock := newTestLock()
+
+	assert.Equal(t, []string{"1.2.3", "1.3.0"}, lock.LockedVersions("app", "https://charts.example.com"))
+	assert.Equal(t, []string{"18.0.1"}, lock.LockedVersions("redis", "oci://registry.example.com/charts/redis"))
+	assert.Empty(t, lock.LockedVersions("app", "https://other.example.com"))
+}
+
+func TestLockedDigests(t *testing.T) {
+	t.Parallel()
+
+	lock := newTestLock()
+
+	assert.Equal(t, []string{"sha256:aaa", "sha256:ccc"}, lock.LockedDigests("app", "https://charts.example.com"))
+	assert.Equal(t, []string{"sha256:bbb"}, lock.LockedDigests("redis", "oci://registry.example.com/charts/redis"))
+	assert.Empty(t, lock.LockedDigests("app", "https://other.example.com"))
+}
+
+func TestLockedDigest(t *testing.T) {
+	t.Parallel()
+
+	lock := newTestLock()
+
+	got, ok := lock.LockedDigest("app", "https://charts.example.com", "1.3.0")
+	assert.True(t, ok)
+	assert.Equal(t, "sha256:ccc", got)
+
+	got, ok = lock.LockedDigest("redis", "oci://registry.example.com/charts/redis", "18.0.1")
+	assert.True(t, ok)
+	assert.Equal(t, "sha256:bbb", got)
+
+	_, ok = lock.LockedDigest("app", "https://charts.example.com", "1.2.4")
+	assert.False(t, ok)
+}
@@ -9,6 +9,7 @@ import (
 	"net/url"
 
 	"github.com/macropower/kclipper/pkg/kube"
+	"github.com/macropower/kclipper/pkg/offline"
 )
 
 // HTTPDoer is the interface for making HTTP requests.
@@ -38,8 +39,13 @@ func FromURLs(ctx context.Context, httpClient HTTPDoer, crdURLs ...*url.URL) ([]
 }
 
 // FromURL reads CRDs from the given HTTP URL and returns the corresponding
-// []kube.Object representation.
+// []kube.Object representation. CRDs are not cached, so in offline mode an
+// [offline.CacheMissError] is returned.
 func FromURL(ctx context.Context, httpClient HTTPDoer, crdURL *url.URL) ([]kube.Object, error) {
+	if offline.Enabled() {
+		return nil, offline.CacheMissError{Resource: "CRDs", Name: crdURL.String()}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, crdURL.String(), http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("create http request: %w", err)
@@ -0,0 +1,519 @@
+package helm
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+const (
+	// cacheLockDir is the directory next to cache entries which holds their
+	// lock files.
+	cacheLockDir = ".locks"
+
+	// cacheLockRetryDelay is the delay between attempts to lock a cache
+	// entry which is locked by another process.
+	cacheLockRetryDelay = 50 * time.Millisecond
+)
+
+var (
+	// ErrCorruptChart indicates that a cached chart archive could not be loaded.
+	ErrCorruptChart = errors.New("corrupt cached chart")
+
+	// ErrChartInUse indicates that a cached chart could not be removed, since
+	// it is locked by another goroutine or process.
+	ErrChartInUse = errors.New("cached chart in use")
+
+	// pruneMu prevents concurrent pulls from pruning the cache at the same time.
+	pruneMu sync.Mutex
+)
+
+// newCacheEntryLock returns the advisory file lock of the cache entry at path.
+// Entries are locked shared while they are read, and exclusively while they
+// are created or removed, so that entries in use by any process are never
+// evicted. Entry names may be as long as the file system allows, so lock files
+// are named by the hash of the entry's name instead. They are left in place,
+// since they cannot be removed without racing other processes that are about
+// to lock them.
+func newCacheEntryLock(path string) (*flock.Flock, error) {
+	dir := filepath.Join(filepath.Dir(path), cacheLockDir)
+
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("create cache lock directory: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(filepath.Base(path)))
+
+	return flock.New(filepath.Join(dir, hex.EncodeToString(sum[:])+".lock")), nil
+}
+
+// lockCacheEntry locks the cache entry at path, and reports whether it exists.
+// Existing entries are locked shared, so that they can be read concurrently.
+// Otherwise, the entry is locked exclusively, so that the caller can create
+// it, after which it may downgrade the lock with [flock.Flock.RLock]. The
+// caller must unlock the returned lock.
+func lockCacheEntry(ctx context.Context, path string) (*flock.Flock, bool, error) {
+	lock, err := newCacheEntryLock(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, err = lock.TryRLockContext(ctx, cacheLockRetryDelay)
+	if err != nil {
+		return nil, false, fmt.Errorf("lock cache entry %q: %w", path, err)
+	}
+
+	exists, err := pathExists(path)
+	if err != nil {
+		_ = lock.Unlock()
+
+		return nil, false, err
+	}
+
+	if exists {
+		return lock, true, nil
+	}
+
+	// Locks cannot be upgraded atomically, so the entry may be created by
+	// another process in between.
+	err = lock.Unlock()
+	if err != nil {
+		return nil, false, fmt.Errorf("unlock cache entry %q: %w", path, err)
+	}
+
+	_, err = lock.TryLockContext(ctx, cacheLockRetryDelay)
+	if err != nil {
+		return nil, false, fmt.Errorf("lock cache entry %q: %w", path, err)
+	}
+
+	exists, err = pathExists(path)
+	if err == nil && exists {
+		// Downgrade the lock, since the entry was created in between.
+		err = lock.RLock()
+		if err != nil {
+			err = fmt.Errorf("lock cache entry %q: %w", path, err)
+		}
+	}
+
+	if err != nil {
+		_ = lock.Unlock()
+
+		return nil, false, err
+	}
+
+	return lock, exists, nil
+}
+
+// pathExists returns true if a file or directory exists at path.
+func pathExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("stat cache entry: %w", err)
+	}
+
+	return true, nil
+}
+
+// WithCacheLimits returns a [ClientOption] that bounds the size of the chart
+// cache. After a chart is pulled, the least recently used charts are evicted
+// until the cache is no larger than maxSize bytes, and charts which have not
+// been used for longer than maxAge are evicted. A zero value disables the
+// respective limit. See [Client.PruneCache].
+func WithCacheLimits(maxSize int64, maxAge time.Duration) ClientOption {
+	return func(c *Client) {
+		c.MaxCacheSize = maxSize
+		c.MaxCacheAge = maxAge
+	}
+}
+
+// CacheEntry is a chart, or a checkout of a Git repository, stored in the
+// [Client]'s [PathCacher].
+type CacheEntry struct {
+	// LastAccess is the last time the chart was pulled or read from the cache.
+	LastAccess time.Time `json:"lastAccess"`
+	// Path is the path of the cached chart archive, or of the checkout.
+	Path string `json:"path"`
+	// Chart is the name of the chart. It is empty for Git checkouts.
+	Chart string `json:"chart"`
+	// RepoURL is the URL of the repository the chart was pulled from.
+	RepoURL string `json:"repoURL"`
+	// Version is the version of the chart, or the commit of a Git checkout.
+	Version string `json:"version"`
+	// Digest is the OCI manifest digest the chart was pinned to, if any.
+	Digest string `json:"digest,omitempty"`
+	// Project is the project the chart was pulled for.
+	Project string `json:"project"`
+	// Verify is the provenance verification mode the chart was pulled with.
+	Verify string `json:"verify,omitempty"`
+	// Size is the size of the cached chart archive or checkout in bytes.
+	Size int64 `json:"size"`
+	// GitCheckout is set if the entry is a checkout of a Git repository.
+	GitCheckout bool `json:"gitCheckout,omitempty"`
+}
+
+// CacheFilter selects [CacheEntry] values. Empty fields match all entries.
+type CacheFilter struct {
+	// AccessedBefore matches entries which were last accessed before it.
+	AccessedBefore time.Time
+	// Chart matches entries with the given chart name.
+	Chart string
+	// RepoURL matches entries pulled from the given repository URL.
+	RepoURL string
+	// Project matches entries pulled for the given project.
+	Project string
+}
+
+// Match returns true if the entry is selected by the filter.
+func (f CacheFilter) Match(e *CacheEntry) bool {
+	switch {
+	case f.Chart != "" && f.Chart != e.Chart:
+		return false
+	case f.RepoURL != "" && f.RepoURL != e.RepoURL:
+		return false
+	case f.Project != "" && f.Project != e.Project:
+		return false
+	case !f.AccessedBefore.IsZero() && !e.LastAccess.Before(f.AccessedBefore):
+		return false
+	}
+
+	return true
+}
+
+// CacheEntries returns the charts and Git checkouts in the [Client]'s
+// [PathCacher] which match filter, sorted by chart, repository, version and
+// project. Entries of all projects are returned unless filtered. Paths whose
+// keys are not chart cache keys are ignored.
+func (c *Client) CacheEntries(filter CacheFilter) ([]*CacheEntry, error) {
+	entries := []*CacheEntry{}
+
+	for keyData, path := range c.Paths.GetPaths() {
+		key := chartCacheKey{}
+
+		err := json.Unmarshal([]byte(keyData), &key)
+		if err != nil || (key.Chart == "" && !key.GitCheckout) {
+			continue
+		}
+
+		fi, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// Removed since the paths were listed.
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("stat cached chart: %w", err)
+		}
+
+		size, err := cacheEntrySize(path, fi)
+		if err != nil {
+			return nil, err
+		}
+
+		e := &CacheEntry{
+			Path:        path,
+			Chart:       key.Chart,
+			RepoURL:     key.URL,
+			Version:     key.Version,
+			Digest:      key.Digest,
+			Project:     key.Project,
+			Verify:      key.Verify,
+			Size:        size,
+			LastAccess:  fi.ModTime(),
+			GitCheckout: key.GitCheckout,
+		}
+
+		if filter.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b *CacheEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.Chart, b.Chart),
+			cmp.Compare(a.RepoURL, b.RepoURL),
+			cmp.Compare(a.Version, b.Version),
+			cmp.Compare(a.Project, b.Project),
+			cmp.Compare(a.Path, b.Path),
+		)
+	})
+
+	return entries, nil
+}
+
+// cacheEntrySize returns the size of the file at path, or of all files within
+// it if it is a directory.
+func cacheEntrySize(path string, fi os.FileInfo) (int64, error) {
+	if !fi.IsDir() {
+		return fi.Size(), nil
+	}
+
+	var size int64
+
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err //nolint:wrapcheck // Wrapped below.
+			}
+
+			size += info.Size()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("get size of cached checkout: %w", err)
+	}
+
+	return size, nil
+}
+
+// RemoveCacheEntries removes the charts in the [Client]'s [PathCacher] which
+// match filter, and returns the removed entries. Charts which are locked by
+// another goroutine or process are in use, and are not removed.
+func (c *Client) RemoveCacheEntries(filter CacheFilter) ([]*CacheEntry, error) {
+	entries, err := c.CacheEntries(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []*CacheEntry{}
+
+	for _, e := range entries {
+		ok, err := c.removeCacheEntry(e)
+		if err != nil {
+			return removed, err
+		}
+
+		if !ok {
+			slog.Warn("skip removal of chart in use",
+				slog.String("chart", e.Chart),
+				slog.String("version", e.Version),
+			)
+
+			continue
+		}
+
+		removed = append(removed, e)
+	}
+
+	return removed, nil
+}
+
+// removeCacheEntry removes the entry if it is not locked by any goroutine or
+// process. It reports whether the entry was removed.
+func (c *Client) removeCacheEntry(e *CacheEntry) (bool, error) {
+	if !c.RepoLock.TryLock(e.Path) {
+		return false, nil
+	}
+	defer c.RepoLock.Unlock(e.Path)
+
+	lock, err := newCacheEntryLock(e.Path)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := lock.TryLock()
+	if err != nil {
+		return false, fmt.Errorf("lock cache entry %q: %w", e.Path, err)
+	}
+
+	if !ok {
+		return false, nil
+	}
+
+	defer lock.Unlock() //nolint:errcheck // Unlocked when the file is closed.
+
+	err = os.RemoveAll(e.Path)
+	if err != nil {
+		return false, fmt.Errorf("remove chart cache at %q: %w", e.Path, err)
+	}
+
+	return true, nil
+}
+
+// VerifyCacheEntry checks that the cached chart can be loaded, and that its
+// metadata matches the entry. Git checkouts are only checked to be
+// directories, since they may hold any number of charts. It returns an error
+// wrapping [ErrCorruptChart] if the check fails.
+func (c *Client) VerifyCacheEntry(e *CacheEntry) error {
+	c.RepoLock.Lock(e.Path)
+	defer c.RepoLock.Unlock(e.Path)
+
+	lock, err := newCacheEntryLock(e.Path)
+	if err != nil {
+		return err
+	}
+
+	err = lock.RLock()
+	if err != nil {
+		return fmt.Errorf("lock cache entry %q: %w", e.Path, err)
+	}
+
+	defer lock.Unlock() //nolint:errcheck // Unlocked when the file is closed.
+
+	if e.GitCheckout {
+		if !dirExists(e.Path) {
+			return fmt.Errorf("%w: %s: checkout is not a directory", ErrCorruptChart, e.Path)
+		}
+
+		return nil
+	}
+
+	chart, err := loadChart(e.Path)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrCorruptChart, e.Path, err)
+	}
+
+	if name := normalizeChartName(e.Chart); chart.Name() != name {
+		return fmt.Errorf("%w: %s: chart name is %q, expected %q",
+			ErrCorruptChart, e.Path, chart.Name(), name)
+	}
+
+	if e.Version != "" && chart.Metadata.Version != e.Version {
+		return fmt.Errorf("%w: %s: chart version is %q, expected %q",
+			ErrCorruptChart, e.Path, chart.Metadata.Version, e.Version)
+	}
+
+	return nil
+}
+
+// PruneCache evicts charts from the [Client]'s [PathCacher] according to its
+// cache limits, least recently used first, and returns the evicted entries.
+// Charts which are locked by another goroutine or process are in use, and are
+// never evicted. Pulled charts stay locked until they are read, see
+// [PulledChart]. See [WithCacheLimits].
+func (c *Client) PruneCache(ctx context.Context) ([]*CacheEntry, error) {
+	pruneMu.Lock()
+	defer pruneMu.Unlock()
+
+	return c.pruneCache(ctx, "")
+}
+
+// pruneCache implements [Client.PruneCache]. The entry at keep is never
+// evicted, so that a chart which was just pulled can be used. The caller must
+// hold pruneMu.
+func (c *Client) pruneCache(ctx context.Context, keep string) ([]*CacheEntry, error) {
+	if c.MaxCacheSize <= 0 && c.MaxCacheAge <= 0 {
+		return nil, nil
+	}
+
+	entries, err := c.CacheEntries(CacheFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(entries, func(a, b *CacheEntry) int {
+		return a.LastAccess.Compare(b.LastAccess)
+	})
+
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+
+	now := time.Now()
+	evicted := []*CacheEntry{}
+
+	for _, e := range entries {
+		expired := c.MaxCacheAge > 0 && now.Sub(e.LastAccess) > c.MaxCacheAge
+		oversize := c.MaxCacheSize > 0 && size > c.MaxCacheSize
+
+		if !expired && !oversize {
+			// Entries are sorted by last access, so the rest are newer.
+			break
+		}
+
+		if e.Path == keep {
+			continue
+		}
+
+		ok, err := c.evictCacheEntry(e)
+		if err != nil {
+			return evicted, err
+		}
+
+		if !ok {
+			slog.DebugContext(ctx, "skip eviction of chart in use",
+				slog.String("chart", e.Chart),
+				slog.String("version", e.Version),
+			)
+
+			continue
+		}
+
+		slog.DebugContext(ctx, "evicted cached chart",
+			slog.String("chart", e.Chart),
+			slog.String("version", e.Version),
+			slog.Int64("size", e.Size),
+			slog.Time("last_access", e.LastAccess),
+		)
+
+		size -= e.Size
+		evicted = append(evicted, e)
+	}
+
+	return evicted, nil
+}
+
+// evictCacheEntry removes the entry if it is not locked by any goroutine or
+// process, and was not accessed since it was listed. It reports whether the
+// entry was removed.
+func (c *Client) evictCacheEntry(e *CacheEntry) (bool, error) {
+	if !c.RepoLock.TryLock(e.Path) {
+		return false, nil
+	}
+	defer c.RepoLock.Unlock(e.Path)
+
+	lock, err := newCacheEntryLock(e.Path)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := lock.TryLock()
+	if err != nil {
+		return false, fmt.Errorf("lock cache entry %q: %w", e.Path, err)
+	}
+
+	if !ok {
+		return false, nil
+	}
+
+	defer lock.Unlock() //nolint:errcheck // Unlocked when the file is closed.
+
+	fi, err := os.Stat(e.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("stat cached chart: %w", err)
+	}
+
+	if fi.ModTime().After(e.LastAccess) {
+		return false, nil
+	}
+
+	err = os.RemoveAll(e.Path)
+	if err != nil {
+		return false, fmt.Errorf("remove chart cache at %q: %w", e.Path, err)
+	}
+
+	return true, nil
+}
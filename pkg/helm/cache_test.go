@@ -0,0 +1,220 @@
+package helm_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+func TestClientCache(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3", "1.2.5"})
+	client := newTestClient(t)
+
+	for _, version := range []string{"1.2.3", "1.2.5"} {
+		pc, err := client.Pull(t.Context(), "test-chart", srv.URL, version, helmrepo.DefaultManager)
+		require.NoError(t, err)
+		require.NoError(t, pc.Close())
+	}
+
+	entries, err := client.CacheEntries(helm.CacheFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	for i, version := range []string{"1.2.3", "1.2.5"} {
+		assert.Equal(t, "test-chart", entries[i].Chart)
+		assert.Equal(t, srv.URL, entries[i].RepoURL)
+		assert.Equal(t, version, entries[i].Version)
+		assert.Equal(t, "test", entries[i].Project)
+		assert.Positive(t, entries[i].Size)
+		require.NoError(t, client.VerifyCacheEntry(entries[i]))
+	}
+
+	filtered, err := client.CacheEntries(helm.CacheFilter{Project: "other"})
+	require.NoError(t, err)
+	assert.Empty(t, filtered)
+
+	// Mark the first entry as stale, and corrupt the second.
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(entries[0].Path, old, old))
+	require.NoError(t, os.WriteFile(entries[1].Path, []byte("not a chart"), 0o600))
+
+	require.ErrorIs(t, client.VerifyCacheEntry(entries[1]), helm.ErrCorruptChart)
+
+	// Pulling a cached chart updates its access time.
+	pc, err := client.Pull(t.Context(), "test-chart", srv.URL, "1.2.5", helmrepo.DefaultManager)
+	require.NoError(t, err)
+	require.NoError(t, pc.Close())
+
+	removed, err := client.RemoveCacheEntries(helm.CacheFilter{
+		AccessedBefore: time.Now().Add(-24 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, "1.2.3", removed[0].Version)
+	assert.NoFileExists(t, removed[0].Path)
+
+	entries, err = client.CacheEntries(helm.CacheFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "1.2.5", entries[0].Version)
+}
+
+func TestClientPruneCache(t *testing.T) {
+	t.Parallel()
+
+	versions := []string{"1.2.3", "1.2.5", "1.3.0"}
+	srv := newChartServer(t, "test-chart", versions)
+	cache := paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder())
+	client := helm.MustNewClient(cache, "test")
+
+	for _, version := range versions {
+		pc, err := client.Pull(t.Context(), "test-chart", srv.URL, version, helmrepo.DefaultManager)
+		require.NoError(t, err)
+		require.NoError(t, pc.Close())
+	}
+
+	entries, err := client.CacheEntries(helm.CacheFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	// Order the last access times by version, oldest first.
+	for i, e := range entries {
+		accessed := time.Now().Add(time.Duration(i-len(entries)) * time.Hour)
+		require.NoError(t, os.Chtimes(e.Path, accessed, accessed))
+	}
+
+	// Without limits, nothing is evicted.
+	evicted, err := client.PruneCache(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, evicted)
+
+	// The oldest entry is in use, so newer entries are evicted instead.
+	client.RepoLock.Lock(entries[0].Path)
+
+	limited := helm.MustNewClient(cache, "other",
+		helm.WithCacheLimits(entries[0].Size, 0),
+	)
+
+	evicted, err = limited.PruneCache(t.Context())
+	require.NoError(t, err)
+	require.Len(t, evicted, 2)
+	assert.Equal(t, "1.2.5", evicted[0].Version)
+	assert.Equal(t, "1.3.0", evicted[1].Version)
+	assert.FileExists(t, entries[0].Path)
+
+	client.RepoLock.Unlock(entries[0].Path)
+
+	// Only the expired entry is evicted.
+	limited = helm.MustNewClient(cache, "other", helm.WithCacheLimits(0, 2*time.Hour))
+
+	pc, err := client.Pull(t.Context(), "test-chart", srv.URL, "1.3.0", helmrepo.DefaultManager)
+	require.NoError(t, err)
+	require.NoError(t, pc.Close())
+
+	evicted, err = limited.PruneCache(t.Context())
+	require.NoError(t, err)
+	require.Len(t, evicted, 1)
+	assert.Equal(t, "1.2.3", evicted[0].Version)
+
+	entries, err = client.CacheEntries(helm.CacheFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "1.3.0", entries[0].Version)
+}
+
+func TestClientPullPrunesCache(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3", "1.2.5"})
+	client := helm.MustNewClient(
+		paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()),
+		"test",
+		helm.WithCacheLimits(1, 0),
+	)
+
+	// The chart that was just pulled is kept, even though it exceeds the limit.
+	for _, version := range []string{"1.2.3", "1.2.5"} {
+		pc, err := client.Pull(t.Context(), "test-chart", srv.URL, version, helmrepo.DefaultManager)
+		require.NoError(t, err)
+		require.NoError(t, pc.Close())
+
+		entries, err := client.CacheEntries(helm.CacheFilter{})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, version, entries[0].Version)
+	}
+}
+
+func TestClientPruneCacheLockedEntries(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+	cache := paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder())
+	client := helm.MustNewClient(cache, "test")
+	limited := helm.MustNewClient(cache, "other", helm.WithCacheLimits(0, time.Hour))
+
+	pc, err := client.Pull(t.Context(), "test-chart", srv.URL, "1.2.3", helmrepo.DefaultManager)
+	require.NoError(t, err)
+
+	entries, err := client.CacheEntries(helm.CacheFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	path := entries[0].Path
+	expire := func() {
+		t.Helper()
+
+		old := time.Now().Add(-24 * time.Hour)
+		require.NoError(t, os.Chtimes(path, old, old))
+	}
+
+	// The chart stays locked after it is pulled, until it is closed.
+	expire()
+
+	evicted, err := limited.PruneCache(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, evicted)
+
+	removed, err := client.RemoveCacheEntries(helm.CacheFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+	assert.FileExists(t, path)
+
+	err = client.CleanChartCache("test-chart", srv.URL, "1.2.3")
+	require.ErrorIs(t, err, helm.ErrChartInUse)
+	assert.FileExists(t, path)
+
+	_, err = pc.Load(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, pc.Close())
+
+	// Entries locked by another process are not evicted either.
+	sum := sha256.Sum256([]byte(filepath.Base(path)))
+	other := flock.New(filepath.Join(filepath.Dir(path), ".locks", hex.EncodeToString(sum[:])+".lock"))
+	require.NoError(t, other.RLock())
+
+	evicted, err = limited.PruneCache(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, evicted)
+	assert.FileExists(t, path)
+
+	require.NoError(t, other.Unlock())
+
+	evicted, err = limited.PruneCache(t.Context())
+	require.NoError(t, err)
+	require.Len(t, evicted, 1)
+	assert.NoFileExists(t, path)
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"text/template"
 	"time"
 
 	"helm.sh/helm/v4/pkg/action"
@@ -33,19 +35,49 @@ var (
 
 // TemplateOpts configures Helm chart template rendering.
 type TemplateOpts struct {
-	ValuesObject         map[string]any
-	TargetRevision       string
-	RepoURL              string
-	ReleaseName          string
-	Namespace            string
-	ChartName            string
-	KubeVersion          string
-	APIVersions          []string
+	ValuesObject map[string]any
+	// CommonLabels are added to the labels of all rendered resources.
+	CommonLabels map[string]string
+	// CommonAnnotations are added to the annotations of all rendered
+	// resources.
+	CommonAnnotations map[string]string
+	TargetRevision    string
+	RepoURL           string
+	ReleaseName       string
+	Namespace         string
+	ChartName         string
+	KubeVersion       string
+	// ArgoCDAppName is the name of the Argo CD application that the rendered
+	// resources belong to. If set, an [ArgoCDTrackingIDAnnotation] is added to
+	// all rendered resources.
+	ArgoCDAppName string
+	Sort          SortOrder
+	// SetValues are applied after ValueFiles and ValuesObject are merged,
+	// like the `--set` flags of `helm template`.
+	SetValues *SetValues
+	// ValueFiles are merged in order, and then with ValuesObject, to produce
+	// the values that the chart is rendered with.
+	ValueFiles  []*ValuesFile
+	APIVersions []string
+	// ClusterScopedKinds are additional cluster-scoped kinds, in the format
+	// `Kind.group`, e.g. `ClusterIssuer.cert-manager.io`. Custom resources
+	// are assumed to be namespaced unless they are listed here, or their CRD
+	// is rendered alongside them.
+	ClusterScopedKinds   []string
+	Lookups              []kube.Object
 	Timeout              time.Duration
 	SkipCRDs             bool
 	PassCredentials      bool
 	SkipSchemaValidation bool
 	SkipHooks            bool
+	// ForceNamespace sets the namespace of all rendered namespaced resources
+	// to [TemplateOpts.Namespace], replacing any namespace set by the chart.
+	// See [TemplateOpts.ClusterScopedKinds] for how namespaced resources are
+	// identified.
+	ForceNamespace bool
+	// CreateNamespace adds a Namespace for [TemplateOpts.Namespace] to the
+	// rendered resources, unless the chart renders one.
+	CreateNamespace bool
 }
 
 // Chart renders Helm chart templates into Kubernetes resources.
@@ -54,6 +86,9 @@ type Chart struct {
 	Client       ChartClient
 	Repos        helmrepo.Getter
 	TemplateOpts *TemplateOpts
+	// RenderCache memoizes the output of [Chart.Template]. It may be nil, in
+	// which case charts are always rendered.
+	RenderCache *RenderCache
 }
 
 // NewChart creates a new [Chart].
@@ -67,7 +102,10 @@ func NewChart(client ChartClient, repos helmrepo.Getter, opts *TemplateOpts) *Ch
 
 // Template templates the Helm [Chart]. The [chart.Chart] and its dependencies
 // are pulled as needed. The rendered output is then split into individual
-// Kubernetes resources and returned as a slice of [kube.Object].
+// Kubernetes resources, modified by the built-in post-renderers configured in
+// [TemplateOpts], and returned as a slice of [kube.Object], ordered according
+// to [TemplateOpts.Sort]. If the [Chart] has a [RenderCache] with
+// output for the same chart and options, loading and templating are skipped.
 func (c *Chart) Template(ctx context.Context) ([]kube.Object, error) {
 	cancel := func() {}
 	if c.TemplateOpts.Timeout > 0 {
@@ -76,6 +114,118 @@ func (c *Chart) Template(ctx context.Context) ([]kube.Object, error) {
 
 	defer cancel()
 
+	pulledChart, err := c.pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tryClose(pulledChart)
+
+	out, err := c.templateCached(ctx, pulledChart)
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := kube.SplitYAML(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrChartTemplateParse, err)
+	}
+
+	objs, err = postRender(objs, c.TemplateOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	SortObjects(objs, c.TemplateOpts.Sort)
+
+	return objs, nil
+}
+
+// templateCached returns the rendered output of pulledChart, using the
+// [Chart]'s [RenderCache] if it has one.
+func (c *Chart) templateCached(ctx context.Context, pulledChart *PulledChart) ([]byte, error) {
+	var (
+		key         string
+		loadedChart *chart.Chart
+	)
+
+	if c.RenderCache != nil {
+		// Dependencies which are not packaged with the chart may resolve to
+		// different charts over time, so they are pulled before the key is
+		// computed, see [RenderCache.key].
+		unpackaged, err := pulledChart.hasUnpackagedDependencies()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrChartLoad, err)
+		}
+
+		if unpackaged {
+			loadedChart, err = loadPulled(ctx, pulledChart)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		key, err = c.RenderCache.key(pulledChart, c.TemplateOpts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrChartTemplate, err)
+		}
+	}
+
+	if key != "" {
+		out, ok, err := c.RenderCache.get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrChartTemplate, err)
+		}
+
+		if ok {
+			slog.DebugContext(ctx, "using cached chart render", slog.String("key", key))
+
+			return out, nil
+		}
+	}
+
+	if loadedChart == nil {
+		var err error
+
+		loadedChart, err = loadPulled(ctx, pulledChart)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := templateData(ctx, loadedChart, c.TemplateOpts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrChartTemplate, err)
+	}
+
+	if key != "" {
+		err := c.RenderCache.put(ctx, key, out)
+		if err != nil {
+			// The render succeeded, so don't fail because it can't be cached.
+			slog.WarnContext(ctx, "cache chart render",
+				slog.String("key", key),
+				slog.Any("err", err),
+			)
+		}
+	}
+
+	return out, nil
+}
+
+// load pulls the Helm [Chart] and loads it, along with its dependencies.
+func (c *Chart) load(ctx context.Context) (*chart.Chart, error) {
+	pulledChart, err := c.pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tryClose(pulledChart)
+
+	return loadPulled(ctx, pulledChart)
+}
+
+// pull pulls the Helm [Chart].
+func (c *Chart) pull(ctx context.Context) (*PulledChart, error) {
 	pulledChart, err := c.Client.Pull(ctx,
 		c.TemplateOpts.ChartName,
 		c.TemplateOpts.RepoURL,
@@ -86,25 +236,47 @@ func (c *Chart) Template(ctx context.Context) ([]kube.Object, error) {
 		return nil, fmt.Errorf("%w: %w", ErrChartPull, err)
 	}
 
+	return pulledChart, nil
+}
+
+// loadPulled loads the pulled chart, along with its dependencies.
+func loadPulled(ctx context.Context, pulledChart *PulledChart) (*chart.Chart, error) {
 	loadedChart, err := pulledChart.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrChartLoad, err)
 	}
 
-	out, err := templateData(ctx, loadedChart, c.TemplateOpts)
+	return loadedChart, nil
+}
+
+func templateData(ctx context.Context, loadedChart *chart.Chart, t *TemplateOpts) ([]byte, error) {
+	rel, err := templateRelease(ctx, loadedChart, t)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrChartTemplate, err)
+		return nil, err
 	}
 
-	objs, err := kube.SplitYAML(out)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrChartTemplateParse, err)
+	manifests := bytes.NewBufferString(rel.Manifest())
+	if !t.SkipHooks {
+		for _, hook := range rel.Hooks() {
+			if hook == nil {
+				continue
+			}
+
+			ha, err := release.NewHookAccessor(hook)
+			if err != nil {
+				return nil, fmt.Errorf("access release hook: %w", err)
+			}
+
+			manifests.WriteString("\n---\n" + ha.Manifest())
+		}
 	}
 
-	return objs, nil
+	return manifests.Bytes(), nil
 }
 
-func templateData(ctx context.Context, loadedChart *chart.Chart, t *TemplateOpts) ([]byte, error) {
+// templateRelease runs a client-only Helm install of loadedChart, and returns
+// the resulting release.
+func templateRelease(ctx context.Context, loadedChart *chart.Chart, t *TemplateOpts) (release.Accessor, error) {
 	var err error
 
 	// Fail open instead of blocking the template.
@@ -123,6 +295,17 @@ func templateData(ctx context.Context, loadedChart *chart.Chart, t *TemplateOpts
 	cfg := &action.Configuration{}
 	cfg.SetLogger(newDebugHandler())
 
+	// Helm has no cluster to query in client-only dry-run mode, so `lookup`
+	// is served from the provided objects instead.
+	if len(t.Lookups) > 0 {
+		lc, err := newLookupClient(t.Lookups)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.CustomTemplateFuncs = template.FuncMap{"lookup": lc.Lookup}
+	}
+
 	ta := action.NewInstall(cfg)
 	// In client-only dry-run mode, Helm substitutes mock capabilities, kube
 	// client, and release storage, applying KubeVersion and appending
@@ -148,11 +331,12 @@ func templateData(ctx context.Context, loadedChart *chart.Chart, t *TemplateOpts
 	ta.IncludeCRDs = !t.SkipCRDs
 	ta.SkipCRDs = t.SkipCRDs
 
-	if t.ValuesObject == nil {
-		t.ValuesObject = make(map[string]any)
+	vals, err := t.values(loadedChart)
+	if err != nil {
+		return nil, err
 	}
 
-	releaser, err := ta.RunWithContext(ctx, loadedChart, t.ValuesObject)
+	releaser, err := ta.RunWithContext(ctx, loadedChart, vals)
 	if err != nil {
 		return nil, fmt.Errorf("execute helm install: %w", err)
 	}
@@ -162,21 +346,5 @@ func templateData(ctx context.Context, loadedChart *chart.Chart, t *TemplateOpts
 		return nil, fmt.Errorf("access release: %w", err)
 	}
 
-	manifests := bytes.NewBufferString(rel.Manifest())
-	if !t.SkipHooks {
-		for _, hook := range rel.Hooks() {
-			if hook == nil {
-				continue
-			}
-
-			ha, err := release.NewHookAccessor(hook)
-			if err != nil {
-				return nil, fmt.Errorf("access release hook: %w", err)
-			}
-
-			manifests.WriteString("\n---\n" + ha.Manifest())
-		}
-	}
-
-	return manifests.Bytes(), nil
+	return rel, nil
 }
@@ -64,6 +64,8 @@ func NewChartFiles(
 
 	chartPath, closer, err := pulledChart.Extract(maxSize)
 	if err != nil {
+		tryClose(pulledChart)
+
 		return nil, fmt.Errorf("%w: %w", ErrChartExtract, err)
 	}
 
@@ -193,9 +195,35 @@ func (c *ChartFiles) GetCRDFiles(gen CRDGenerator, match func(string) bool) ([]k
 	return crdFiles, nil
 }
 
-// Dispose releases the resources associated with the extracted chart.
+// Version returns the exact version of the pulled chart. See
+// [PulledChart.Version].
+func (c *ChartFiles) Version() string {
+	return c.pulledChart.Version()
+}
+
+// Digest returns the digest of the pulled chart archive. See
+// [PulledChart.Digest].
+func (c *ChartFiles) Digest() (string, error) {
+	return c.pulledChart.Digest()
+}
+
+// Dependencies loads the chart and returns the dependencies that were pulled
+// for it. See [PulledChart.Dependencies].
+func (c *ChartFiles) Dependencies(ctx context.Context) ([]*PulledChart, error) {
+	_, err := c.pulledChart.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrChartLoad, err)
+	}
+
+	return c.pulledChart.Dependencies(), nil
+}
+
+// Dispose releases the resources associated with the extracted chart, and
+// closes the pulled chart, see [PulledChart.Close].
 func (c *ChartFiles) Dispose() {
 	if c.closer != nil {
 		tryClose(c.closer)
 	}
+
+	tryClose(c.pulledChart)
 }
@@ -0,0 +1,71 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// ChartInfo describes a loaded Helm chart. Metadata is the chart's
+// Chart.yaml, and Dependencies holds the [ChartInfo] of each dependency that
+// was resolved while loading the chart.
+type ChartInfo struct {
+	Metadata     *chart.Metadata `json:"metadata"`
+	Dependencies []*ChartInfo    `json:"dependencies"`
+}
+
+// NewChartInfo creates a new [ChartInfo] from a loaded [chart.Chart]. The
+// dependencies of c are walked recursively.
+func NewChartInfo(c *chart.Chart) *ChartInfo {
+	deps := make([]*ChartInfo, 0, len(c.Dependencies()))
+	for _, dep := range c.Dependencies() {
+		deps = append(deps, NewChartInfo(dep))
+	}
+
+	md := c.Metadata
+	if md == nil {
+		md = &chart.Metadata{}
+	}
+
+	return &ChartInfo{
+		Metadata:     md,
+		Dependencies: deps,
+	}
+}
+
+// ToMap converts the [ChartInfo] to a map, using the same keys as Chart.yaml.
+func (i *ChartInfo) ToMap() (map[string]any, error) {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return nil, fmt.Errorf("marshal chart info: %w", err)
+	}
+
+	m := map[string]any{}
+
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal chart info: %w", err)
+	}
+
+	return m, nil
+}
+
+// Info pulls and loads the Helm [Chart], and returns a [ChartInfo] describing
+// the chart and its resolved dependencies. No templates are rendered.
+func (c *Chart) Info(ctx context.Context) (*ChartInfo, error) {
+	cancel := func() {}
+	if c.TemplateOpts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.TemplateOpts.Timeout)
+	}
+
+	defer cancel()
+
+	loadedChart, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewChartInfo(loadedChart), nil
+}
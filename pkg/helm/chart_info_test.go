@@ -0,0 +1,63 @@
+package helm_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/helmrepo"
+)
+
+func TestHelmChartInfo(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "dep-chart", []string{"1.2.3", "1.2.5"})
+
+	repoRoot := t.TempDir()
+	chartDir := filepath.Join(repoRoot, "charts", "parent-chart")
+	require.NoError(t, os.MkdirAll(chartDir, 0o700))
+
+	chartYAML := fmt.Sprintf(
+		"apiVersion: v2\nname: parent-chart\nversion: 0.1.0\nappVersion: \"1.16.0\"\n"+
+			"kubeVersion: \">=1.25.0-0\"\nannotations:\n  example.com/foo: bar\n"+
+			"dependencies:\n  - name: dep-chart\n    version: \"~1.2.0\"\n    repository: %s\n",
+		srv.URL,
+	)
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chartYAML), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte("{}\n"), 0o600))
+
+	repoMgr := helmrepo.NewManager(helmrepo.WithAllowedPaths(repoRoot, repoRoot))
+
+	c := helm.NewChart(newTestClient(t), repoMgr, &helm.TemplateOpts{
+		ChartName: "parent-chart",
+		RepoURL:   "./charts",
+	})
+
+	info, err := c.Info(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, "parent-chart", info.Metadata.Name)
+	assert.Equal(t, "1.16.0", info.Metadata.AppVersion)
+	assert.Equal(t, ">=1.25.0-0", info.Metadata.KubeVersion)
+	assert.Equal(t, map[string]string{"example.com/foo": "bar"}, info.Metadata.Annotations)
+
+	require.Len(t, info.Dependencies, 1)
+	assert.Equal(t, "dep-chart", info.Dependencies[0].Metadata.Name)
+	assert.Equal(t, "1.2.5", info.Dependencies[0].Metadata.Version)
+
+	got, err := info.ToMap()
+	require.NoError(t, err)
+
+	metadata, ok := got["metadata"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "1.16.0", metadata["appVersion"])
+
+	deps, ok := got["dependencies"].([]any)
+	require.True(t, ok)
+	require.Len(t, deps, 1)
+}
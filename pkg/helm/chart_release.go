@@ -0,0 +1,211 @@
+package helm
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"helm.sh/helm/v4/pkg/release"
+
+	releasev1 "helm.sh/helm/v4/pkg/release/v1"
+
+	"github.com/macropower/kclipper/pkg/kube"
+)
+
+// Hook is a Helm hook rendered from a [Chart].
+type Hook struct {
+	// Object is the hook's Kubernetes resource.
+	Object kube.Object
+	// Name is the name of the hook's Kubernetes resource.
+	Name string
+	// Kind is the kind of the hook's Kubernetes resource.
+	Kind string
+	// Path is the chart path of the template that the hook was rendered from.
+	Path string
+	// Events are the hook events (e.g. pre-install, post-upgrade, test)
+	// that the hook runs on.
+	Events []string
+	// DeletePolicies control when the hook's resource is deleted.
+	DeletePolicies []string
+	// Weight orders the hook among others with the same event.
+	Weight int
+}
+
+// ToMap converts the [Hook] to a map.
+func (h *Hook) ToMap() map[string]any {
+	return map[string]any{
+		"name":           h.Name,
+		"kind":           h.Kind,
+		"path":           h.Path,
+		"events":         h.Events,
+		"weight":         h.Weight,
+		"deletePolicies": h.DeletePolicies,
+		"resource":       map[string]any(h.Object),
+	}
+}
+
+// Release is the detailed output of templating a Helm [Chart]. Unlike
+// [Chart.Template], hooks are kept separate from the chart's resources, and
+// the chart's rendered NOTES.txt is included.
+type Release struct {
+	// Hooks maps each hook event to the hooks that run on it, in the order
+	// that Helm would run them (by weight, then by name). A hook with multiple
+	// events is included under each of them.
+	Hooks map[string][]*Hook
+	// Notes is the rendered NOTES.txt of the chart.
+	Notes string
+	// Resources are the chart's Kubernetes resources, excluding hooks, ordered
+	// according to [TemplateOpts.Sort].
+	Resources []kube.Object
+}
+
+// ToMap converts the [Release] to a map.
+func (r *Release) ToMap() map[string]any {
+	hooks := make(map[string]any, len(r.Hooks))
+	for event, eventHooks := range r.Hooks {
+		hookMaps := make([]any, 0, len(eventHooks))
+		for _, h := range eventHooks {
+			hookMaps = append(hookMaps, h.ToMap())
+		}
+
+		hooks[event] = hookMaps
+	}
+
+	return map[string]any{
+		"resources": kube.ObjectsToMaps(r.Resources),
+		"hooks":     hooks,
+		"notes":     r.Notes,
+	}
+}
+
+// TemplateRelease templates the Helm [Chart] like [Chart.Template], but
+// returns a [Release] containing the chart's resources, hooks, and notes.
+// If [TemplateOpts.SkipHooks] is set, no hooks are returned. The built-in
+// post-renderers are applied to both resources and hooks.
+func (c *Chart) TemplateRelease(ctx context.Context) (*Release, error) {
+	cancel := func() {}
+	if c.TemplateOpts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.TemplateOpts.Timeout)
+	}
+
+	defer cancel()
+
+	loadedChart, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := templateRelease(ctx, loadedChart, c.TemplateOpts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrChartTemplate, err)
+	}
+
+	objs, err := kube.SplitYAML([]byte(rel.Manifest()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrChartTemplateParse, err)
+	}
+
+	hooks := map[string][]*Hook{}
+	if !c.TemplateOpts.SkipHooks {
+		hooks, err = newHooks(rel.Hooks())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	objs, err = postRenderRelease(objs, hooks, c.TemplateOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	SortObjects(objs, c.TemplateOpts.Sort)
+
+	return &Release{
+		Resources: objs,
+		Hooks:     hooks,
+		Notes:     rel.Notes(),
+	}, nil
+}
+
+// newHooks converts release hooks into [Hook]s grouped by event.
+func newHooks(releaseHooks []release.Hook) (map[string][]*Hook, error) {
+	hooks := map[string][]*Hook{}
+
+	for _, rh := range releaseHooks {
+		h, ok := rh.(*releasev1.Hook)
+		if !ok || h == nil {
+			continue
+		}
+
+		objs, err := kube.SplitYAML([]byte(h.Manifest))
+		if err != nil {
+			return nil, fmt.Errorf("%w: hook %q: %w", ErrChartTemplateParse, h.Path, err)
+		}
+
+		for _, obj := range objs {
+			hook := &Hook{
+				Object:         obj,
+				Name:           h.Name,
+				Kind:           h.Kind,
+				Path:           h.Path,
+				Weight:         h.Weight,
+				Events:         make([]string, 0, len(h.Events)),
+				DeletePolicies: make([]string, 0, len(h.DeletePolicies)),
+			}
+
+			for _, dp := range h.DeletePolicies {
+				hook.DeletePolicies = append(hook.DeletePolicies, dp.String())
+			}
+
+			for _, event := range h.Events {
+				hook.Events = append(hook.Events, event.String())
+				hooks[event.String()] = append(hooks[event.String()], hook)
+			}
+		}
+	}
+
+	for _, eventHooks := range hooks {
+		slices.SortStableFunc(eventHooks, func(a, b *Hook) int {
+			return cmp.Or(
+				cmp.Compare(a.Weight, b.Weight),
+				cmp.Compare(a.Name, b.Name),
+			)
+		})
+	}
+
+	return hooks, nil
+}
+
+// postRenderRelease applies the built-in post-renderers configured in t to the
+// resources and hooks of a [Release], and returns the resources.
+func postRenderRelease(objs []kube.Object, hooks map[string][]*Hook, t *TemplateOpts) ([]kube.Object, error) {
+	if !t.hasPostRender() {
+		return objs, nil
+	}
+
+	// Hooks with multiple events are listed under each of them, but must only
+	// be post-rendered once.
+	seen := map[*Hook]bool{}
+	hookObjs := []kube.Object{}
+
+	for _, eventHooks := range hooks {
+		for _, h := range eventHooks {
+			if !seen[h] {
+				seen[h] = true
+				hookObjs = append(hookObjs, h.Object)
+			}
+		}
+	}
+
+	pr, err := newPostRenderer(slices.Concat(objs, hookObjs), t)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, obj := range hookObjs {
+		pr.apply(obj)
+	}
+
+	return pr.render(objs), nil
+}
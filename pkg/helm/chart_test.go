@@ -5,6 +5,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -369,6 +370,376 @@ func TestHelmChartReleaseName(t *testing.T) {
 	}
 }
 
+func TestHelmChartLookups(t *testing.T) {
+	t.Parallel()
+
+	secret := func(namespace, password string) kube.Object {
+		return kube.Object{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]any{"name": "db-password", "namespace": namespace},
+			"data":       map[string]any{"password": password},
+		}
+	}
+	configMap := func(name string) kube.Object {
+		return kube.Object{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]any{"name": name, "namespace": "default"},
+		}
+	}
+
+	tcs := map[string]struct {
+		err          error
+		lookups      []kube.Object
+		wantPassword string
+		wantCount    string
+	}{
+		"no lookups": {
+			wantPassword: "Z2VuZXJhdGVk",
+			wantCount:    "0",
+		},
+		"existing secret": {
+			lookups:      []kube.Object{secret("default", "ZXhpc3Rpbmc=")},
+			wantPassword: "ZXhpc3Rpbmc=",
+			wantCount:    "0",
+		},
+		"secret in other namespace": {
+			lookups:      []kube.Object{secret("other", "ZXhpc3Rpbmc=")},
+			wantPassword: "Z2VuZXJhdGVk",
+			wantCount:    "0",
+		},
+		"list": {
+			lookups:      []kube.Object{configMap("a"), configMap("b"), secret("default", "ZXhpc3Rpbmc=")},
+			wantPassword: "ZXhpc3Rpbmc=",
+			wantCount:    "2",
+		},
+		"invalid lookup": {
+			lookups: []kube.Object{{"apiVersion": "v1", "kind": "Secret"}},
+			err:     helm.ErrInvalidLookup,
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := helm.NewChart(helmtest.DefaultTestClient, helmrepo.DefaultManager, &helm.TemplateOpts{
+				ChartName: "lookup",
+				RepoURL:   "./testdata",
+				Namespace: "default",
+				Lookups:   tc.lookups,
+			})
+
+			objs, err := c.Template(t.Context())
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, objs, 2)
+
+			for _, obj := range objs {
+				data, ok := obj["data"].(map[string]any)
+				require.True(t, ok)
+
+				switch obj.GetKind() {
+				case "Secret":
+					assert.Equal(t, tc.wantPassword, data["password"])
+				case "ConfigMap":
+					assert.Equal(t, tc.wantCount, data["count"])
+				}
+			}
+		})
+	}
+}
+
+func TestHelmChartTemplateRelease(t *testing.T) {
+	t.Parallel()
+
+	tcs := map[string]struct {
+		wantHooks map[string][]string
+		skipHooks bool
+	}{
+		"with hooks": {
+			wantHooks: map[string][]string{
+				"pre-install": {"release-bootstrap", "release-migrate"},
+				"pre-upgrade": {"release-migrate"},
+			},
+		},
+		"skip hooks": {
+			skipHooks: true,
+			wantHooks: map[string][]string{},
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := helm.NewChart(helmtest.DefaultTestClient, helmrepo.DefaultManager, &helm.TemplateOpts{
+				ChartName: "release",
+				RepoURL:   "./testdata",
+				Namespace: "default",
+				SkipHooks: tc.skipHooks,
+			})
+
+			rel, err := c.TemplateRelease(t.Context())
+			require.NoError(t, err)
+
+			require.Len(t, rel.Resources, 1)
+			assert.Equal(t, "release-config", rel.Resources[0].GetName())
+			assert.Equal(t, "Installed release in default.\n", rel.Notes)
+
+			gotHooks := map[string][]string{}
+			for event, hooks := range rel.Hooks {
+				for _, h := range hooks {
+					assert.Equal(t, h.Name, h.Object.GetName())
+					gotHooks[event] = append(gotHooks[event], h.Name)
+				}
+			}
+
+			assert.Equal(t, tc.wantHooks, gotHooks)
+
+			if tc.skipHooks {
+				return
+			}
+
+			migrate := rel.Hooks["pre-upgrade"][0]
+			assert.Equal(t, "Job", migrate.Kind)
+			assert.Equal(t, 5, migrate.Weight)
+			assert.Equal(t, []string{"pre-install", "pre-upgrade"}, migrate.Events)
+			assert.Equal(t, []string{"before-hook-creation", "hook-succeeded"}, migrate.DeletePolicies)
+			assert.Equal(t, "release/templates/hooks.yaml", migrate.Path)
+		})
+	}
+}
+
+func TestHelmChartPostRender(t *testing.T) {
+	t.Parallel()
+
+	tcs := map[string]struct {
+		err  error
+		opts *helm.TemplateOpts
+		want map[string]map[string]any
+	}{
+		"none": {
+			opts: &helm.TemplateOpts{},
+			want: map[string]map[string]any{
+				"CustomResourceDefinition": {"name": "widgets.example.com"},
+				"ConfigMap": {
+					"name":      "post-render-config",
+					"namespace": "other",
+					"labels":    map[string]any{"app": "post-render"},
+				},
+				"ClusterRole":   {"name": "post-render-role"},
+				"Widget":        {"name": "post-render-widget"},
+				"ClusterIssuer": {"name": "post-render-issuer"},
+			},
+		},
+		"force and create namespace": {
+			opts: &helm.TemplateOpts{
+				Namespace:       "default",
+				ForceNamespace:  true,
+				CreateNamespace: true,
+			},
+			want: map[string]map[string]any{
+				"Namespace":                {"name": "default"},
+				"CustomResourceDefinition": {"name": "widgets.example.com"},
+				"ConfigMap": {
+					"name":      "post-render-config",
+					"namespace": "default",
+					"labels":    map[string]any{"app": "post-render"},
+				},
+				"ClusterRole": {"name": "post-render-role"},
+				"Widget":      {"name": "post-render-widget"},
+				// Without its CRD, the ClusterIssuer is assumed to be namespaced.
+				"ClusterIssuer": {"name": "post-render-issuer", "namespace": "default"},
+			},
+		},
+		"cluster scoped kinds": {
+			opts: &helm.TemplateOpts{
+				Namespace:          "default",
+				ForceNamespace:     true,
+				ArgoCDAppName:      "my-app",
+				ClusterScopedKinds: []string{"ClusterIssuer.cert-manager.io"},
+			},
+			want: map[string]map[string]any{
+				"CustomResourceDefinition": {
+					"name": "widgets.example.com",
+					"annotations": map[string]any{
+						helm.ArgoCDTrackingIDAnnotation: "my-app:apiextensions.k8s.io/CustomResourceDefinition:/widgets.example.com",
+					},
+				},
+				"ConfigMap": {
+					"name":      "post-render-config",
+					"namespace": "default",
+					"labels":    map[string]any{"app": "post-render"},
+					"annotations": map[string]any{
+						helm.ArgoCDTrackingIDAnnotation: "my-app:/ConfigMap:default/post-render-config",
+					},
+				},
+				"ClusterRole": {
+					"name": "post-render-role",
+					"annotations": map[string]any{
+						helm.ArgoCDTrackingIDAnnotation: "my-app:rbac.authorization.k8s.io/ClusterRole:/post-render-role",
+					},
+				},
+				"Widget": {
+					"name": "post-render-widget",
+					"annotations": map[string]any{
+						helm.ArgoCDTrackingIDAnnotation: "my-app:example.com/Widget:/post-render-widget",
+					},
+				},
+				"ClusterIssuer": {
+					"name": "post-render-issuer",
+					"annotations": map[string]any{
+						helm.ArgoCDTrackingIDAnnotation: "my-app:cert-manager.io/ClusterIssuer:/post-render-issuer",
+					},
+				},
+			},
+		},
+		"common labels and annotations": {
+			opts: &helm.TemplateOpts{
+				CommonLabels:      map[string]string{"app": "common", "team": "a"},
+				CommonAnnotations: map[string]string{"example.com/note": "b"},
+			},
+			want: map[string]map[string]any{
+				"CustomResourceDefinition": {
+					"name":        "widgets.example.com",
+					"labels":      map[string]any{"app": "common", "team": "a"},
+					"annotations": map[string]any{"example.com/note": "b"},
+				},
+				"ConfigMap": {
+					"name":        "post-render-config",
+					"namespace":   "other",
+					"labels":      map[string]any{"app": "common", "team": "a"},
+					"annotations": map[string]any{"example.com/note": "b"},
+				},
+				"ClusterRole": {
+					"name":        "post-render-role",
+					"labels":      map[string]any{"app": "common", "team": "a"},
+					"annotations": map[string]any{"example.com/note": "b"},
+				},
+				"Widget": {
+					"name":        "post-render-widget",
+					"labels":      map[string]any{"app": "common", "team": "a"},
+					"annotations": map[string]any{"example.com/note": "b"},
+				},
+				"ClusterIssuer": {
+					"name":        "post-render-issuer",
+					"labels":      map[string]any{"app": "common", "team": "a"},
+					"annotations": map[string]any{"example.com/note": "b"},
+				},
+			},
+		},
+		"argo cd tracking": {
+			opts: &helm.TemplateOpts{
+				Namespace:     "default",
+				ArgoCDAppName: "my-app",
+			},
+			want: map[string]map[string]any{
+				"CustomResourceDefinition": {
+					"name": "widgets.example.com",
+					"annotations": map[string]any{
+						helm.ArgoCDTrackingIDAnnotation: "my-app:apiextensions.k8s.io/CustomResourceDefinition:/widgets.example.com",
+					},
+				},
+				"ConfigMap": {
+					"name":      "post-render-config",
+					"namespace": "other",
+					"labels":    map[string]any{"app": "post-render"},
+					"annotations": map[string]any{
+						helm.ArgoCDTrackingIDAnnotation: "my-app:/ConfigMap:other/post-render-config",
+					},
+				},
+				"ClusterRole": {
+					"name": "post-render-role",
+					"annotations": map[string]any{
+						helm.ArgoCDTrackingIDAnnotation: "my-app:rbac.authorization.k8s.io/ClusterRole:/post-render-role",
+					},
+				},
+				"Widget": {
+					"name": "post-render-widget",
+					"annotations": map[string]any{
+						helm.ArgoCDTrackingIDAnnotation: "my-app:example.com/Widget:/post-render-widget",
+					},
+				},
+				"ClusterIssuer": {
+					"name": "post-render-issuer",
+					"annotations": map[string]any{
+						helm.ArgoCDTrackingIDAnnotation: "my-app:cert-manager.io/ClusterIssuer:default/post-render-issuer",
+					},
+				},
+			},
+		},
+		"force namespace without namespace": {
+			opts: &helm.TemplateOpts{ForceNamespace: true},
+			err:  helm.ErrPostRender,
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.opts.ChartName = "post-render"
+			tc.opts.RepoURL = "./testdata"
+
+			objs, err := helm.NewChart(helmtest.DefaultTestClient, helmrepo.DefaultManager, tc.opts).
+				Template(t.Context())
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			got := map[string]map[string]any{}
+			for _, obj := range objs {
+				metadata, ok := obj["metadata"].(map[string]any)
+				require.True(t, ok)
+
+				got[obj.GetKind()] = metadata
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHelmChartTemplateReleasePostRender(t *testing.T) {
+	t.Parallel()
+
+	c := helm.NewChart(helmtest.DefaultTestClient, helmrepo.DefaultManager, &helm.TemplateOpts{
+		ChartName:    "release",
+		RepoURL:      "./testdata",
+		Namespace:    "default",
+		CommonLabels: map[string]string{"team": "a"},
+	})
+
+	rel, err := c.TemplateRelease(t.Context())
+	require.NoError(t, err)
+
+	objs := slices.Clone(rel.Resources)
+	for _, hooks := range rel.Hooks {
+		for _, h := range hooks {
+			objs = append(objs, h.Object)
+		}
+	}
+
+	require.Len(t, objs, 4)
+
+	for _, obj := range objs {
+		metadata, ok := obj["metadata"].(map[string]any)
+		require.True(t, ok)
+
+		labels, ok := metadata["labels"].(map[string]any)
+		require.True(t, ok, obj.GetName())
+		assert.Equal(t, "a", labels["team"], obj.GetName())
+	}
+}
+
 func BenchmarkHelmChart(b *testing.B) {
 	c := helm.NewChart(helmtest.DefaultTestClient, helmrepo.DefaultManager, &helm.TemplateOpts{
 		ChartName:      "podinfo",
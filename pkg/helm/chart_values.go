@@ -0,0 +1,59 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"helm.sh/helm/v4/pkg/chart/common/util"
+
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+// ErrChartValues indicates an error occurred while computing chart values.
+var ErrChartValues = errors.New("chart values")
+
+// Values pulls and loads the Helm [Chart], and returns the values that Helm
+// would render its templates with. These are the chart's default values,
+// coalesced with the defaults of any enabled subcharts, and then with
+// [TemplateOpts.ValueFiles], [TemplateOpts.ValuesObject] and
+// [TemplateOpts.SetValues] (if any) in the same way as `helm template`.
+func (c *Chart) Values(ctx context.Context) (map[string]any, error) {
+	cancel := func() {}
+	if c.TemplateOpts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.TemplateOpts.Timeout)
+	}
+
+	defer cancel()
+
+	loadedChart, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vals, err := c.TemplateOpts.values(loadedChart)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrChartValues, err)
+	}
+
+	// Disable subcharts via conditions and tags, and apply import-values,
+	// before coalescing (as is done by Helm's install action).
+	err = chartutil.ProcessDependencies(loadedChart, vals)
+	if err != nil {
+		return nil, fmt.Errorf("%w: process dependencies: %w", ErrChartValues, err)
+	}
+
+	coalesced, err := util.CoalesceValues(loadedChart, vals)
+	if err != nil {
+		return nil, fmt.Errorf("%w: coalesce values: %w", ErrChartValues, err)
+	}
+
+	if !c.TemplateOpts.SkipSchemaValidation {
+		err = util.ValidateAgainstSchema(loadedChart, coalesced)
+		if err != nil {
+			return nil, fmt.Errorf("%w: validate values: %w", ErrChartValues, err)
+		}
+	}
+
+	return coalesced.AsMap(), nil
+}
@@ -0,0 +1,92 @@
+package helm_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/helmrepo"
+)
+
+func TestHelmChartValues(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "dep-chart", []string{"1.2.3"})
+
+	repoRoot := t.TempDir()
+	chartDir := filepath.Join(repoRoot, "charts", "parent-chart")
+	require.NoError(t, os.MkdirAll(chartDir, 0o700))
+
+	chartYAML := fmt.Sprintf(
+		"apiVersion: v2\nname: parent-chart\nversion: 0.1.0\ndependencies:\n"+
+			"  - name: dep-chart\n    version: 1.2.3\n    repository: %s\n    condition: dep-chart.enabled\n",
+		srv.URL,
+	)
+	valuesYAML := "image:\n  tag: v1\n  pullPolicy: IfNotPresent\ndep-chart:\n  enabled: true\n"
+
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chartYAML), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte(valuesYAML), 0o600))
+
+	repoMgr := helmrepo.NewManager(helmrepo.WithAllowedPaths(repoRoot, repoRoot))
+
+	tcs := map[string]struct {
+		values  map[string]any
+		wantTag string
+		wantDep map[string]any
+	}{
+		"defaults": {
+			wantTag: "v1",
+			wantDep: map[string]any{"enabled": true, "replicas": float64(1)},
+		},
+		"user values": {
+			values: map[string]any{
+				"image":     map[string]any{"tag": "v2"},
+				"dep-chart": map[string]any{"replicas": 3},
+			},
+			wantTag: "v2",
+			wantDep: map[string]any{"enabled": true, "replicas": 3},
+		},
+		"disabled dependency": {
+			values: map[string]any{
+				"dep-chart": map[string]any{"enabled": false},
+			},
+			wantTag: "v1",
+			wantDep: map[string]any{"enabled": false},
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := helm.NewChart(newTestClient(t), repoMgr, &helm.TemplateOpts{
+				ChartName:    "parent-chart",
+				RepoURL:      "./charts",
+				ValuesObject: tc.values,
+			})
+
+			got, err := c.Values(t.Context())
+			require.NoError(t, err)
+
+			image, ok := got["image"].(map[string]any)
+			require.True(t, ok)
+			assert.Equal(t, tc.wantTag, image["tag"])
+			assert.Equal(t, "IfNotPresent", image["pullPolicy"])
+
+			dep, ok := got["dep-chart"].(map[string]any)
+			require.True(t, ok)
+
+			for k, v := range tc.wantDep {
+				assert.Equal(t, v, dep[k], k)
+			}
+
+			if enabled, ok := tc.wantDep["enabled"].(bool); ok && !enabled {
+				assert.NotContains(t, dep, "replicas")
+			}
+		})
+	}
+}
@@ -3,20 +3,26 @@ package helm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
+	"github.com/gofrs/flock"
 	"helm.sh/helm/v4/pkg/downloader"
 	"helm.sh/helm/v4/pkg/getter"
 	"helm.sh/helm/v4/pkg/registry"
 
-	chartrepo "helm.sh/helm/v4/pkg/repo/v1"
-
 	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/offline"
 	"github.com/macropower/kclipper/pkg/paths"
 	"github.com/macropower/kclipper/pkg/syncs"
 )
@@ -24,11 +30,15 @@ import (
 var (
 	globalLock = syncs.NewKeyLock()
 
+	// ErrChartVerify indicates that a chart's provenance could not be verified.
+	ErrChartVerify = errors.New("verify chart provenance")
+
 	// DefaultClient is a [Client] configured with default paths and the
 	// ARGOCD_APP_PROJECT_NAME environment variable.
 	DefaultClient = MustNewClient(
 		paths.NewStaticTempPaths(filepath.Join(os.TempDir(), "charts"), paths.NewBase64PathEncoder()),
 		os.Getenv("ARGOCD_APP_PROJECT_NAME"),
+		WithIndexCache(NewIndexCache(filepath.Join(os.TempDir(), "indexes"), DefaultIndexCacheTTL)),
 	)
 )
 
@@ -50,20 +60,67 @@ type ChartClient interface {
 // Client pulls and caches Helm charts from local and remote repositories.
 // Create instances with [NewClient] or [MustNewClient].
 type Client struct {
-	Paths     PathCacher
-	RepoLock  syncs.KeyLocker
-	rc        *registry.Client
-	transport *http.Transport
-	helmHome  string
-	Project   string
-	Proxy     string
-	NoProxy   string
+	Paths    PathCacher
+	RepoLock syncs.KeyLocker
+	// Lock pins and verifies pulled charts, see [WithLock]. It may be nil.
+	Lock ChartLocker
+	// IndexCache caches the indexes of HTTP(S) repositories, see
+	// [WithIndexCache].
+	IndexCache *IndexCache
+	// credentials is nil if [Client.DisableCredentialStores] is set.
+	credentials *credentialStore
+	rc          *registry.Client
+	transport   *http.Transport
+	// httpClients are the clients returned by [Client.httpClient].
+	httpClients map[tlsSettings]*http.Client
+	helmHome    string
+	Project     string
+	Proxy       string
+	NoProxy     string
+	// Verify is the default provenance verification mode, used for
+	// repositories which do not set their own.
+	Verify helmrepo.VerifyMode
+	// Keyring is the default keyring used to verify chart provenance files.
+	Keyring string
+	// ValuesFileHosts are the hosts that values files may be fetched from,
+	// see [WithValuesFileURLs]. If empty, any host is allowed.
+	ValuesFileHosts []string
+	// MaxCacheSize is the maximum size of the chart cache in bytes, see
+	// [WithCacheLimits]. Zero means unlimited.
+	MaxCacheSize int64
+	// MaxCacheAge is the maximum time since a cached chart was last used, see
+	// [WithCacheLimits]. Zero means unlimited.
+	MaxCacheAge time.Duration
+	// MaxValuesFileSize is the maximum size of a values file fetched from a
+	// URL in bytes, see [WithValuesFileURLs]. Zero means
+	// [DefaultMaxValuesFileSize].
+	MaxValuesFileSize int64
+	httpClientsMu     sync.Mutex
+	// Offline refuses network access, so that only cached charts can be
+	// pulled. See [WithOffline].
+	Offline bool
+	// DisableCredentialStores stops the [Client] from reading credentials
+	// from Docker's and Helm's config files (including credential helpers)
+	// for repositories which do not configure their own. See
+	// [WithCredentialStores].
+	DisableCredentialStores bool
+	// PlainHTTP accesses OCI registries over plain HTTP rather than HTTPS,
+	// see [WithPlainHTTP].
+	PlainHTTP bool
 }
 
 // ClientOption configures a [Client].
 //
 // Available options:
 //   - [WithProxy]
+//   - [WithVerify]
+//   - [WithLock]
+//   - [WithOffline]
+//   - [WithCacheLimits]
+//   - [WithIndexCache]
+//   - [WithCredentialStores]
+//   - [WithPlainHTTP]
+//   - [WithValuesFileURLs]
 type ClientOption func(*Client)
 
 // WithProxy returns a [ClientOption] that routes chart downloads through the
@@ -76,28 +133,75 @@ func WithProxy(proxy, noProxy string) ClientOption {
 	}
 }
 
-// NewClient creates a new [Client].
-func NewClient(pc PathCacher, project string, opts ...ClientOption) (*Client, error) {
-	tmpDir, err := os.MkdirTemp("", "helm")
-	if err != nil {
-		return nil, fmt.Errorf("create temporary directory for helm: %w", err)
+// WithVerify returns a [ClientOption] that sets the default provenance
+// verification mode and keyring. These apply to charts pulled from
+// repositories which do not set their own [helmrepo.VerifyMode], including
+// chart dependencies.
+func WithVerify(mode helmrepo.VerifyMode, keyring string) ClientOption {
+	return func(c *Client) {
+		c.Verify = mode
+		c.Keyring = keyring
 	}
+}
 
+// WithOffline returns a [ClientOption] that enables offline mode. In offline
+// mode, charts which are not in the [PathCacher] are not pulled, and an
+// [offline.CacheMissError] is returned instead. Offline mode is also enabled
+// for all clients by [offline.SetEnabled].
+func WithOffline(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.Offline = enabled
+	}
+}
+
+// WithPlainHTTP returns a [ClientOption] that accesses OCI registries over
+// plain HTTP rather than HTTPS, like Helm's `--plain-http` flag. It should
+// only be enabled for local registries.
+func WithPlainHTTP(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.PlainHTTP = enabled
+	}
+}
+
+// NewClient creates a new [Client].
+func NewClient(pc PathCacher, project string, opts ...ClientOption) (*Client, error) {
 	c := &Client{
 		Paths:    pc,
 		RepoLock: globalLock,
-		helmHome: tmpDir,
 		Project:  project,
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	var err error
+
+	c.Verify, err = helmrepo.GetVerifyMode(string(c.Verify))
+	if err != nil {
+		return nil, fmt.Errorf("set default verification: %w", err)
+	}
+
 	c.transport = c.proxyTransport()
 
+	if !c.DisableCredentialStores {
+		c.credentials, err = newCredentialStore()
+		if err != nil {
+			return nil, fmt.Errorf("create credential store: %w", err)
+		}
+	}
+
+	var rcHTTPClient *http.Client
+
 	rcOpts := []registry.ClientOption{registry.ClientOptEnableCache(true)}
 	if c.transport != nil {
-		rcOpts = append(rcOpts, registry.ClientOptHTTPClient(&http.Client{Transport: c.transport}))
+		rcHTTPClient = &http.Client{Transport: c.transport}
+		rcOpts = append(rcOpts, registry.ClientOptHTTPClient(rcHTTPClient))
+	}
+
+	rcOpts = append(rcOpts, c.registryClientOptions(rcHTTPClient)...)
+
+	if c.PlainHTTP {
+		rcOpts = append(rcOpts, registry.ClientOptPlainHTTP())
 	}
 
 	rc, err := registry.NewClient(rcOpts...)
@@ -107,6 +211,16 @@ func NewClient(pc PathCacher, project string, opts ...ClientOption) (*Client, er
 
 	c.rc = rc
 
+	// Created last, so that it is not leaked if the client cannot be created.
+	c.helmHome, err = os.MkdirTemp("", "helm")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary directory for helm: %w", err)
+	}
+
+	if c.IndexCache == nil {
+		c.IndexCache = NewIndexCache(filepath.Join(c.helmHome, "indexes"), 0)
+	}
+
 	return c, nil
 }
 
@@ -120,62 +234,172 @@ func MustNewClient(pc PathCacher, project string, opts ...ClientOption) *Client
 	return c
 }
 
+// Close removes the [Client]'s temporary Helm home, which holds downloaded
+// content and repository indexes, and closes idle connections. Charts in the
+// [PathCacher] are kept. The [Client] must not be used after it is closed.
+func (c *Client) Close() error {
+	c.httpClientsMu.Lock()
+	for _, client := range c.httpClients {
+		client.CloseIdleConnections()
+	}
+	c.httpClientsMu.Unlock()
+
+	err := os.RemoveAll(c.helmHome)
+	if err != nil {
+		return fmt.Errorf("remove helm home: %w", err)
+	}
+
+	return nil
+}
+
 // Pull pulls the Helm chart and returns the path to the chart directory or
 // .tar.gz file. Pulled charts will be stored in the injected [PathCacher], and
 // subsequent requests will try to use [PathCacher] rather than re-pulling the
-// chart.
+// chart. If version is a semver constraint, it is first resolved to the highest
+// matching version in the repository, which is then used to pull and cache the
+// chart. See [PulledChart.Version]. Charts in Git repositories are checked out
+// at the commit that the repository URL's ref resolves to, and the version is
+// ignored. In offline mode, only cached charts can be pulled, see
+// [WithOffline]. Repositories without credentials use those from Docker's and
+// Helm's config files, unless disabled with [WithCredentialStores]. Cached
+// charts are locked until [PulledChart.Close] is called, so that they are not
+// evicted while they are in use.
 func (c *Client) Pull(ctx context.Context, chart, repo, version string, repos helmrepo.Getter) (*PulledChart, error) {
 	hr, err := repos.Get(repo)
 	if err != nil {
 		return nil, fmt.Errorf("get repo: %q: %w", repo, err)
 	}
 
+	version, dgst, err := ParseTargetRevision(version)
+	if err != nil {
+		return nil, fmt.Errorf("parse target revision: %w", err)
+	}
+
+	if dgst != "" && !hr.IsOCI() {
+		return nil, fmt.Errorf("%w: digests are only supported for OCI repositories: %q", ErrInvalidDigest, repo)
+	}
+
 	pc := &PulledChart{
 		chart:  chart,
+		repo:   repo,
 		repos:  repos,
 		client: c,
 	}
 
 	if hr.IsLocal() {
-		chartPath, err := c.getLocalChart(chart, hr)
+		chartPath, err := c.getLocalChart(chart, version, hr)
 		if err != nil {
 			return nil, fmt.Errorf("get local chart: %w", err)
 		}
 
 		pc.path = chartPath
+		pc.root = hr.Root
 
 		return pc, err
 	}
 
-	chartPath, err := c.getCachedOrRemoteChart(ctx, chart, version, hr)
+	if hr.IsGit() {
+		err := c.getGitChart(ctx, pc, hr)
+		if err != nil {
+			return nil, fmt.Errorf("get git chart: %w", err)
+		}
+
+		return pc, nil
+	}
+
+	if IsVersionConstraint(version) {
+		if dgst != "" {
+			return nil, fmt.Errorf("%w: cannot pin version constraint %q to a digest", ErrInvalidDigest, version)
+		}
+
+		resolved, err := c.resolveConstraint(ctx, chart, repo, version, hr)
+		if err != nil {
+			return nil, err
+		}
+
+		version = resolved
+	}
+
+	chartPath, lock, err := c.getCachedOrRemoteChart(ctx, chart, version, dgst, hr)
 	if err != nil {
 		return nil, fmt.Errorf("get cached or remote chart: %w", err)
 	}
 
+	err = c.verifyLock(chart, repo, version, chartPath)
+	if err != nil {
+		_ = lock.Unlock()
+
+		return nil, err
+	}
+
 	pc.path = chartPath
+	pc.version = version
+	pc.holdEntry(lock)
 
 	return pc, nil
 }
 
-// CleanChartCache removes the cached chart directory for the given chart.
+// CleanChartCache removes the cached chart directory for the given chart. If
+// the chart is locked by another goroutine or process, it is not removed, and
+// an error wrapping [ErrChartInUse] is returned.
 func (c *Client) CleanChartCache(chart, repo, version string) error {
-	cachePath, err := c.getCachedChartPath(chart, repo, version)
+	version, dgst, err := ParseTargetRevision(version)
+	if err != nil {
+		return fmt.Errorf("parse target revision: %w", err)
+	}
+
+	cachePath, err := c.getCachedChartPath(chart, repo, version, dgst, c.Verify, c.Keyring)
 	if err != nil {
 		return fmt.Errorf("get cached chart path: %w", err)
 	}
 
-	err = os.RemoveAll(cachePath)
+	removed, err := c.removeCacheEntry(&CacheEntry{Path: cachePath})
 	if err != nil {
-		return fmt.Errorf("remove chart cache at %q: %w", cachePath, err)
+		return err
+	}
+
+	if !removed {
+		return fmt.Errorf("%w: %s %s from %q", ErrChartInUse, chart, version, repo)
 	}
 
 	return nil
 }
 
-func (c *Client) getCachedChartPath(chart, repo, version string) (string, error) {
-	keyData, err := json.Marshal(
-		map[string]string{"url": repo, "chart": chart, "version": version, "project": c.Project},
-	)
+// chartCacheKey identifies a pulled chart in the [Client]'s [PathCacher].
+// Field order determines the encoded key, so it must not change.
+type chartCacheKey struct {
+	Chart   string `json:"chart"`
+	Digest  string `json:"digest,omitempty"`
+	Keyring string `json:"keyring,omitempty"`
+	Project string `json:"project"`
+	URL     string `json:"url"`
+	Verify  string `json:"verify,omitempty"`
+	Version string `json:"version"`
+	// GitCheckout is set for checkouts of Git repositories, which may hold
+	// any number of charts, so Chart is empty.
+	GitCheckout bool `json:"gitCheckout,omitempty"`
+}
+
+func (c *Client) getCachedChartPath(
+	chart, repo, version, dgst string,
+	verify helmrepo.VerifyMode,
+	keyring string,
+) (string, error) {
+	key := chartCacheKey{URL: repo, Chart: chart, Version: version, Digest: dgst, Project: c.Project}
+
+	// Charts are verified when they are pulled, so a verified chart must not
+	// share a cache entry with an unverified one.
+	if verify.Enabled() {
+		key.Verify = string(verify)
+		key.Keyring = keyring
+	}
+
+	return c.cachedPath(key)
+}
+
+// cachedPath returns the path in the [PathCacher] for key.
+func (c *Client) cachedPath(key chartCacheKey) (string, error) {
+	keyData, err := json.Marshal(key)
 	if err != nil {
 		return "", fmt.Errorf("marshal key data: %w", err)
 	}
@@ -188,53 +412,228 @@ func (c *Client) getCachedChartPath(chart, repo, version string) (string, error)
 	return chartPath, nil
 }
 
-func (c *Client) getLocalChart(chart string, repo *helmrepo.Repo) (string, error) {
-	chartPath := filepath.Join(repo.URL.String(), chart)
-	if !dirExists(chartPath) {
-		return "", fmt.Errorf("chart directory does not exist: %q", chartPath)
+// verification returns the provenance verification mode and keyring to use
+// for charts pulled from repo, falling back to the [Client]'s defaults.
+func (c *Client) verification(repo *helmrepo.Repo) (helmrepo.VerifyMode, string) {
+	if repo == nil || repo.Verify == helmrepo.VerifyModeDefault {
+		return c.Verify, c.Keyring
 	}
 
-	return chartPath, nil
+	keyring := repo.Keyring.String()
+	if keyring == "" {
+		keyring = c.Keyring
+	}
+
+	return repo.Verify, keyring
+}
+
+// offline returns true if the [Client] must not access the network.
+func (c *Client) offline() bool {
+	return c.Offline || offline.Enabled()
+}
+
+// getLocalChart returns the path to chart in the local repository repo, see
+// [localChartPath].
+func (c *Client) getLocalChart(chart, version string, repo *helmrepo.Repo) (string, error) {
+	return localChartPath(repo.URL.String(), chart, version)
+}
+
+// localChartPath returns the path to chart in the local repository at
+// repoPath. The repository may be a packaged chart itself, or a directory
+// containing chart as a directory or packaged chart. Otherwise, version
+// selects a packaged `<chart>-<version>.tgz` in the repository, see
+// [findChartArchive].
+func localChartPath(repoPath, chart, version string) (string, error) {
+	if isChartArchive(repoPath) {
+		exists, err := fileExists(repoPath)
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			return "", fmt.Errorf("chart archive does not exist: %q", repoPath)
+		}
+
+		return repoPath, nil
+	}
+
+	chartPath := filepath.Join(repoPath, chart)
+	if dirExists(chartPath) {
+		return chartPath, nil
+	}
+
+	if isChartArchive(chartPath) {
+		exists, err := fileExists(chartPath)
+		if err != nil {
+			return "", err
+		}
+
+		if exists {
+			return chartPath, nil
+		}
+	}
+
+	archivePath, err := findChartArchive(repoPath, normalizeChartName(chart), version)
+	if err != nil {
+		return "", fmt.Errorf("chart directory does not exist: %q: %w", chartPath, err)
+	}
+
+	return archivePath, nil
 }
 
+// findChartArchive returns the path to the packaged chart named
+// `<chart>-<version>.tgz` in dir. If version is a constraint or empty, the
+// highest matching version is used.
+func findChartArchive(dir, chart, version string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("read repository directory: %w", err)
+	}
+
+	versions := semver.Collection{}
+
+	for _, e := range entries {
+		v, ok := strings.CutPrefix(e.Name(), chart+"-")
+		if !ok || e.IsDir() {
+			continue
+		}
+
+		v, ok = strings.CutSuffix(v, ".tgz")
+		if !ok {
+			continue
+		}
+
+		sv, err := semver.NewVersion(v)
+		if err != nil {
+			// E.g. another chart whose name starts with the same prefix.
+			continue
+		}
+
+		versions = append(versions, sv)
+	}
+
+	sort.Sort(sort.Reverse(versions))
+
+	tags := make([]string, 0, len(versions))
+	for _, v := range versions {
+		tags = append(tags, v.Original())
+	}
+
+	match, err := registry.GetTagMatchingVersionOrConstraint(tags, version)
+	if err != nil {
+		return "", fmt.Errorf("no packaged chart %q matching version %q: %w", chart, version, err)
+	}
+
+	return filepath.Join(dir, chart+"-"+match+".tgz"), nil
+}
+
+// isChartArchive returns true if path has the extension of a packaged chart.
+func isChartArchive(path string) bool {
+	return strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz")
+}
+
+// getCachedOrRemoteChart returns the path to the chart in the [PathCacher],
+// pulling it if it is not cached. The cache entry is returned locked shared,
+// see [lockCacheEntry], and the caller must unlock it once the chart is read.
 func (c *Client) getCachedOrRemoteChart(
 	ctx context.Context,
-	chart, version string,
+	chart, version, dgst string,
 	repo *helmrepo.Repo,
-) (string, error) {
-	cachedChartPath, err := c.getCachedChartPath(chart, repo.URL.String(), version)
+) (string, *flock.Flock, error) {
+	verify, keyring := c.verification(repo)
+
+	cachedChartPath, err := c.getCachedChartPath(chart, repo.URL.String(), version, dgst, verify, keyring)
 	if err != nil {
-		return "", fmt.Errorf("get cached chart path: %w", err)
+		return "", nil, fmt.Errorf("get cached chart path: %w", err)
 	}
 
 	c.RepoLock.Lock(cachedChartPath)
 	defer c.RepoLock.Unlock(cachedChartPath)
 
 	// Check if chart tar is already downloaded.
-	exists, err := fileExists(cachedChartPath)
+	lock, exists, err := lockCacheEntry(ctx, cachedChartPath)
 	if err != nil {
-		return "", fmt.Errorf("check cached chart path: %w", err)
+		return "", nil, fmt.Errorf("check cached chart path: %w", err)
 	}
 
 	if !exists {
-		err := c.pullRemoteChart(ctx, chart, version, cachedChartPath, repo)
+		if c.offline() {
+			_ = lock.Unlock()
+
+			rev := version
+			if dgst != "" {
+				rev = strings.TrimPrefix(version+"@"+dgst, "@")
+			}
+
+			return "", nil, offline.CacheMissError{
+				Resource: "chart",
+				Name:     chart,
+				Version:  rev,
+				Source:   repo.URL.String(),
+			}
+		}
+
+		err := c.pullRemoteChart(ctx, chart, version, dgst, cachedChartPath, repo)
+		if err == nil {
+			// Downgrade the lock, so that other processes can read the chart.
+			err = lock.RLock()
+		}
+
 		if err != nil {
-			return "", fmt.Errorf("pull remote chart: %w", err)
+			_ = lock.Unlock()
+
+			return "", nil, fmt.Errorf("pull remote chart: %w", err)
 		}
+
+		c.pruneCacheAfterPull(ctx, cachedChartPath)
+
+		return cachedChartPath, lock, nil
+	}
+
+	// Record the access, so that the chart cache can be pruned by last use.
+	now := time.Now()
+
+	err = os.Chtimes(cachedChartPath, now, now)
+	if err != nil {
+		slog.DebugContext(ctx, "update cached chart access time",
+			slog.String("path", cachedChartPath),
+			slog.Any("err", err),
+		)
 	}
 
-	return cachedChartPath, nil
+	return cachedChartPath, lock, nil
+}
+
+// pruneCacheAfterPull prunes the chart cache after the chart at pulledPath was
+// added to it. Pruning is skipped if it is already running in another
+// goroutine, and errors are logged rather than failing the pull.
+func (c *Client) pruneCacheAfterPull(ctx context.Context, pulledPath string) {
+	if !pruneMu.TryLock() {
+		return
+	}
+	defer pruneMu.Unlock()
+
+	_, err := c.pruneCache(ctx, pulledPath)
+	if err != nil {
+		slog.WarnContext(ctx, "prune chart cache", slog.Any("err", err))
+	}
 }
 
-func (c *Client) pullRemoteChart(ctx context.Context, chart, version, dstPath string, repo *helmrepo.Repo) error {
-	// Create empty temp directory to download the chart into.
+func (c *Client) pullRemoteChart(
+	ctx context.Context,
+	chart, version, dgst, dstPath string,
+	repo *helmrepo.Repo,
+) error {
+	repo = c.withStoredCredentials(ctx, repo)
+
+	// Create empty temp directory to download the chart into. If ctx is
+	// cancelled, it is removed once the abandoned download has stopped, which
+	// may be after this function returns.
 	tempDest, err := os.MkdirTemp("", "kclipper-*")
 	if err != nil {
 		return fmt.Errorf("create temporary destination directory: %w", err)
 	}
 
-	defer func() { _ = os.RemoveAll(tempDest) }()
-
 	logger := slog.With(
 		slog.String("chart", chart),
 	)
@@ -270,22 +669,46 @@ func (c *Client) pullRemoteChart(ctx context.Context, chart, version, dstPath st
 		passCredentials = repo.PassCredentials
 	}
 
+	verify, keyring := c.verification(repo)
+	if verify.Enabled() && keyring == "" {
+		_ = os.RemoveAll(tempDest)
+
+		return fmt.Errorf("%w: a keyring is required to verify charts", ErrChartVerify)
+	}
+
+	dlVerify := downloader.VerifyNever
+	if verify.Enabled() {
+		// Download provenance files without verifying them, so that a missing
+		// provenance file can be distinguished from an invalid signature.
+		dlVerify = downloader.VerifyLater
+	}
+
 	getters, err := c.getters(certFile, keyFile, caFile, insecureSkipVerify)
 	if err != nil {
+		_ = os.RemoveAll(tempDest)
+
 		return fmt.Errorf("create getters: %w", err)
 	}
 
+	getterOpts := []getter.Option{
+		getter.WithBasicAuth(username, password),
+		getter.WithPassCredentialsAll(passCredentials),
+		getter.WithTLSClientConfig(certFile, keyFile, caFile),
+		getter.WithInsecureSkipVerifyTLS(insecureSkipVerify),
+		getter.WithRegistryClient(c.rc),
+	}
+
+	// Helm's getters do not accept a context, so bound each request by the
+	// context's deadline to keep abandoned downloads from running forever.
+	if deadline, ok := ctx.Deadline(); ok {
+		getterOpts = append(getterOpts, getter.WithTimeout(time.Until(deadline)))
+	}
+
 	dl := &downloader.ChartDownloader{
-		Out:     io.Discard,
-		Verify:  downloader.VerifyNever,
-		Getters: getters,
-		Options: []getter.Option{
-			getter.WithBasicAuth(username, password),
-			getter.WithPassCredentialsAll(passCredentials),
-			getter.WithTLSClientConfig(certFile, keyFile, caFile),
-			getter.WithInsecureSkipVerifyTLS(insecureSkipVerify),
-			getter.WithRegistryClient(c.rc),
-		},
+		Out:            io.Discard,
+		Verify:         dlVerify,
+		Getters:        getters,
+		Options:        getterOpts,
 		RegistryClient: c.rc,
 		ContentCache:   filepath.Join(c.helmHome, "content"),
 	}
@@ -293,61 +716,138 @@ func (c *Client) pullRemoteChart(ctx context.Context, chart, version, dstPath st
 	logger.InfoContext(ctx, "pulling chart",
 		slog.String("chart_ref", chartRef),
 		slog.String("version", version),
+		slog.String("digest", dgst),
 		slog.String("destination", tempDest),
 		slog.String("repo_url", repoURL),
 		slog.Bool("insecure_skip_tls_verify", insecureSkipVerify),
 		slog.Bool("pass_credentials_all", passCredentials),
+		slog.String("verify", string(verify)),
 	)
 
-	pull := func() error {
+	// pull downloads the chart into tempDest, and returns the path of the
+	// downloaded archive.
+	pull := func() (string, error) {
 		if repoURL != "" {
-			chartURL, err := chartrepo.FindChartInRepoURL(repoURL, chartRef, getters,
-				chartrepo.WithChartVersion(version),
-				chartrepo.WithUsernamePassword(username, password),
-				chartrepo.WithClientTLS(certFile, keyFile, caFile),
-				chartrepo.WithInsecureSkipTLSVerify(insecureSkipVerify),
-				chartrepo.WithPassCredentialsAll(passCredentials),
-			)
+			chartURL, err := c.findChartInIndex(ctx, chartRef, version, repo)
 			if err != nil {
-				return fmt.Errorf("find chart in repo: %w", err)
+				return "", fmt.Errorf("find chart in repo: %w", err)
 			}
 
 			chartRef = chartURL
 		}
 
-		saved, _, err := dl.DownloadTo(chartRef, version, tempDest)
-		if err != nil {
-			return fmt.Errorf("download chart: %w", err)
-		}
-
-		logger.DebugContext(ctx, "moving pulled chart",
-			slog.String("src", saved),
-			slog.String("dst", dstPath),
+		var (
+			saved string
+			err   error
 		)
 
-		err = os.Rename(saved, dstPath)
+		if dgst != "" {
+			// Pull by digest rather than letting Helm resolve the tag, so that a
+			// re-pushed tag is reported instead of being silently used.
+			saved, err = c.pullOCIChartDigest(chartRef, version, dgst, tempDest, verify.Enabled())
+		} else {
+			saved, _, err = dl.DownloadTo(chartRef, version, tempDest)
+		}
+
 		if err != nil {
-			return fmt.Errorf("rename file from %q to %q: %w", saved, dstPath, err)
+			return "", fmt.Errorf("download chart: %w", err)
 		}
 
-		return nil
+		if verify.Enabled() {
+			err := verifyChart(ctx, logger, saved, keyring, verify)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		return saved, nil
 	}
 
-	done := make(chan error, 1)
+	type pullResult struct {
+		err   error
+		saved string
+	}
+
+	done := make(chan pullResult, 1)
 	go func() {
-		done <- pull()
+		saved, err := pull()
+		done <- pullResult{saved: saved, err: err}
 	}()
 
+	var result pullResult
+
 	select {
 	case <-ctx.Done():
+		// The download cannot be interrupted, so clean up after it in the
+		// background. It is never moved into the cache, since the caller
+		// no longer holds the lock on dstPath once this function returns.
+		go func() {
+			<-done
+
+			_ = os.RemoveAll(tempDest)
+		}()
+
 		return fmt.Errorf("execute helm pull: %w", ctx.Err())
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("execute helm pull: %w", err)
-		}
+	case result = <-done:
+	}
+
+	defer os.RemoveAll(tempDest) //nolint:errcheck // Best-effort cleanup.
+
+	if result.err != nil {
+		return fmt.Errorf("execute helm pull: %w", result.err)
+	}
+
+	// The caller holds the lock on dstPath, so the cache entry is only
+	// created while it is locked.
+	logger.DebugContext(ctx, "moving pulled chart",
+		slog.String("src", result.saved),
+		slog.String("dst", dstPath),
+	)
+
+	err = os.Rename(result.saved, dstPath)
+	if err != nil {
+		return fmt.Errorf("rename file from %q to %q: %w", result.saved, dstPath, err)
 	}
 
 	logger.DebugContext(ctx, "chart pull complete")
 
 	return nil
 }
+
+// verifyChart verifies the provenance file of the chart archive at chartPath
+// against keyring. In [helmrepo.VerifyModeIfPossible], a chart without a
+// provenance file is allowed.
+func verifyChart(
+	ctx context.Context,
+	logger *slog.Logger,
+	chartPath, keyring string,
+	mode helmrepo.VerifyMode,
+) error {
+	provPath := chartPath + ".prov"
+
+	exists, err := fileExists(provPath)
+	if err != nil {
+		return fmt.Errorf("%w: check provenance file: %w", ErrChartVerify, err)
+	}
+
+	if !exists {
+		if mode == helmrepo.VerifyModeAlways {
+			return fmt.Errorf("%w: provenance file not found for %s", ErrChartVerify, filepath.Base(chartPath))
+		}
+
+		logger.WarnContext(ctx, "provenance file not found, skipping chart verification")
+
+		return nil
+	}
+
+	ver, err := downloader.VerifyChart(chartPath, provPath, keyring)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrChartVerify, filepath.Base(chartPath), err)
+	}
+
+	logger.DebugContext(ctx, "verified chart provenance",
+		slog.String("file_hash", ver.FileHash),
+	)
+
+	return nil
+}
@@ -4,19 +4,30 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/opencontainers/go-digest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v4/pkg/provenance"
+	"helm.sh/helm/v4/pkg/registry"
 
+	"github.com/macropower/kclipper/pkg/chartlock"
 	"github.com/macropower/kclipper/pkg/helm"
 	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/offline"
 	"github.com/macropower/kclipper/pkg/paths"
 )
 
@@ -24,13 +35,26 @@ import (
 func chartArchive(t *testing.T, name, version string) []byte {
 	t.Helper()
 
+	return chartArchiveWithDependencies(t, name, version, "")
+}
+
+// chartArchiveWithDependencies builds a minimal Helm chart .tgz in memory,
+// whose Chart.yaml declares the given YAML list of dependencies.
+func chartArchiveWithDependencies(t *testing.T, name, version, dependencies string) []byte {
+	t.Helper()
+
+	chartYAML := fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\n", name, version)
+	if dependencies != "" {
+		chartYAML += "dependencies:\n" + dependencies
+	}
+
 	files := []struct {
 		path    string
 		content string
 	}{
 		{
 			path:    name + "/Chart.yaml",
-			content: fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\n", name, version),
+			content: chartYAML,
 		},
 		{
 			path:    name + "/values.yaml",
@@ -105,6 +129,104 @@ func newChartServer(t *testing.T, name string, versions []string) *httptest.Serv
 	return srv
 }
 
+const ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+// newOCIServer serves the chart name from an OCI registry over plain HTTP, at
+// `<host>/charts/<name>`, with a tag for each version. It returns the server,
+// and the manifest digest of each version.
+func newOCIServer(t *testing.T, name string, versions []string) (*httptest.Server, map[string]string) {
+	t.Helper()
+
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+	digests := map[string]string{}
+
+	descriptor := func(mediaType string, data []byte) map[string]any {
+		dgst := digest.FromBytes(data).String()
+		blobs[dgst] = data
+
+		return map[string]any{"mediaType": mediaType, "digest": dgst, "size": len(data)}
+	}
+
+	for _, version := range versions {
+		config, err := json.Marshal(map[string]string{"apiVersion": "v2", "name": name, "version": version})
+		require.NoError(t, err)
+
+		manifest, err := json.Marshal(map[string]any{
+			"schemaVersion": 2,
+			"mediaType":     ociManifestMediaType,
+			"config":        descriptor(registry.ConfigMediaType, config),
+			"layers": []any{
+				descriptor(registry.ChartLayerMediaType, chartArchive(t, name, version)),
+			},
+		})
+		require.NoError(t, err)
+
+		dgst := digest.FromBytes(manifest).String()
+		manifests[version] = manifest
+		manifests[dgst] = manifest
+		digests[version] = dgst
+	}
+
+	prefix := "/v2/charts/" + name
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET "+prefix+"/manifests/{ref}", func(w http.ResponseWriter, r *http.Request) {
+		manifest, ok := manifests[r.PathValue("ref")]
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", ociManifestMediaType)
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(manifest).String())
+		w.Header().Set("Content-Length", strconv.Itoa(len(manifest)))
+		_, _ = w.Write(manifest)
+	})
+	mux.HandleFunc("GET "+prefix+"/blobs/{digest}", func(w http.ResponseWriter, r *http.Request) {
+		blob, ok := blobs[r.PathValue("digest")]
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(blob)))
+		_, _ = w.Write(blob)
+	})
+	mux.HandleFunc("GET "+prefix+"/tags/list", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"name": "charts/" + name, "tags": versions}))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv, digests
+}
+
+// requireBasicAuth wraps the handler of srv, so that requests must be
+// authenticated with the given username and password.
+func requireBasicAuth(srv *httptest.Server, username, password string) {
+	next := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok || u != username || p != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func newTestClient(t *testing.T) *helm.Client {
 	t.Helper()
 
@@ -158,10 +280,315 @@ func TestClientPullVersions(t *testing.T) {
 			loadedChart, err := pulledChart.Load(t.Context())
 			require.NoError(t, err)
 			assert.Equal(t, tc.want, loadedChart.Metadata.Version)
+
+			if tc.version != "" {
+				assert.Equal(t, tc.want, pulledChart.Version())
+			}
+		})
+	}
+}
+
+func TestClientPullResolvedVersionCache(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3", "1.2.5", "1.3.0"})
+
+	var downloads atomic.Int32
+
+	mux := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".tgz") {
+			downloads.Add(1)
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+
+	client := newTestClient(t)
+
+	// Constraints are resolved before the cache is checked, so they share the
+	// cache entry of the version they resolve to.
+	for _, version := range []string{"1.2.5", "~1.2.0", ">=1.2.4 <1.3"} {
+		pulledChart, err := client.Pull(t.Context(), "test-chart", srv.URL, version, helmrepo.DefaultManager)
+		require.NoError(t, err)
+		assert.Equal(t, "1.2.5", pulledChart.Version())
+	}
+
+	assert.Equal(t, int32(1), downloads.Load())
+
+	_, err := client.Pull(t.Context(), "test-chart", srv.URL, "~2.0.0", helmrepo.DefaultManager)
+	require.ErrorIs(t, err, helm.ErrResolveVersion)
+}
+
+func TestClientPullLock(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3", "1.2.5"})
+	lockedDigest := digest.FromBytes(chartArchive(t, "test-chart", "1.2.3")).String()
+
+	tcs := map[string]struct {
+		lockedChart string
+		version     string
+		digest      string
+		wantVersion string
+		wantErr     error
+	}{
+		"constraint uses locked version": {
+			version:     "~1.2.0",
+			digest:      lockedDigest,
+			wantVersion: "1.2.3",
+		},
+		"exact version matches": {
+			version:     "1.2.3",
+			digest:      lockedDigest,
+			wantVersion: "1.2.3",
+		},
+		"version not locked": {
+			version: "1.2.5",
+			digest:  lockedDigest,
+			wantErr: helm.ErrChartNotLocked,
+		},
+		"chart not locked": {
+			lockedChart: "other-chart",
+			version:     "1.2.5",
+			digest:      lockedDigest,
+			wantVersion: "1.2.5",
+		},
+		"digest mismatch": {
+			version: "1.2.3",
+			digest:  digest.FromString("other").String(),
+			wantErr: helm.ErrChartLockMismatch,
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lockedChart := tc.lockedChart
+			if lockedChart == "" {
+				lockedChart = "test-chart"
+			}
+
+			lock := chartlock.New()
+			lock.Charts["test"] = &chartlock.Chart{
+				Chart:   lockedChart,
+				RepoURL: srv.URL,
+				Version: "1.2.3",
+				Digest:  tc.digest,
+			}
+
+			client := helm.MustNewClient(
+				paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()),
+				"test",
+				helm.WithLock(lock),
+			)
+
+			pulledChart, err := client.Pull(t.Context(), "test-chart", srv.URL, tc.version, helmrepo.DefaultManager)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantVersion, pulledChart.Version())
+		})
+	}
+}
+
+func TestClientPullOffline(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3", "1.2.5"})
+
+	cache := paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder())
+
+	_, err := helm.MustNewClient(cache, "test").
+		Pull(t.Context(), "test-chart", srv.URL, "1.2.3", helmrepo.DefaultManager)
+	require.NoError(t, err)
+
+	var requests atomic.Int32
+
+	mux := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		mux.ServeHTTP(w, r)
+	})
+
+	client := helm.MustNewClient(cache, "test", helm.WithOffline(true))
+
+	pulledChart, err := client.Pull(t.Context(), "test-chart", srv.URL, "1.2.3", helmrepo.DefaultManager)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", pulledChart.Version())
+
+	for _, version := range []string{"1.2.5", "~1.2.0"} {
+		_, err = client.Pull(t.Context(), "test-chart", srv.URL, version, helmrepo.DefaultManager)
+		require.ErrorIs(t, err, offline.ErrOffline)
+
+		var missErr offline.CacheMissError
+		require.ErrorAs(t, err, &missErr)
+		assert.Equal(t, "test-chart", missErr.Name)
+		assert.Equal(t, version, missErr.Version)
+	}
+
+	assert.Equal(t, int32(0), requests.Load())
+}
+
+func TestClientClose(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+	cache := paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder())
+
+	client := helm.MustNewClient(cache, "test")
+
+	_, err := client.Pull(t.Context(), "test-chart", srv.URL, "1.2.3", helmrepo.DefaultManager)
+	require.NoError(t, err)
+	require.NoError(t, client.Close())
+
+	// Pulled charts outlive the client.
+	_, err = helm.MustNewClient(cache, "test", helm.WithOffline(true)).
+		Pull(t.Context(), "test-chart", srv.URL, "1.2.3", helmrepo.DefaultManager)
+	require.NoError(t, err)
+}
+
+func TestClientPullLocalArchive(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	vendorDir := filepath.Join(root, "vendor")
+	require.NoError(t, os.MkdirAll(vendorDir, 0o700))
+
+	for _, version := range []string{"1.2.3", "1.2.5", "1.3.0"} {
+		archivePath := filepath.Join(vendorDir, fmt.Sprintf("test-chart-%s.tgz", version))
+		require.NoError(t, os.WriteFile(archivePath, chartArchive(t, "test-chart", version), 0o600))
+	}
+
+	// Archives of other charts with the same prefix are ignored.
+	otherPath := filepath.Join(vendorDir, "test-chart-extra-1.4.0.tgz")
+	require.NoError(t, os.WriteFile(otherPath, chartArchive(t, "test-chart-extra", "1.4.0"), 0o600))
+
+	repos := helmrepo.NewManager(helmrepo.WithAllowedPaths(root, root))
+	client := newTestClient(t)
+
+	tcs := map[string]struct {
+		err     string
+		repoURL string
+		version string
+		want    string
+	}{
+		"exact version": {
+			repoURL: "./vendor",
+			version: "1.2.3",
+			want:    "1.2.3",
+		},
+		"version constraint": {
+			repoURL: "file://./vendor",
+			version: "~1.2.0",
+			want:    "1.2.5",
+		},
+		"latest when empty": {
+			repoURL: "./vendor",
+			want:    "1.3.0",
+		},
+		"archive path": {
+			repoURL: "./vendor/test-chart-1.2.5.tgz",
+			want:    "1.2.5",
+		},
+		"archive file URL": {
+			repoURL: "file://./vendor/test-chart-1.2.3.tgz",
+			want:    "1.2.3",
+		},
+		"missing version": {
+			repoURL: "./vendor",
+			version: "2.0.0",
+			err:     `no packaged chart "test-chart" matching version "2.0.0"`,
+		},
+		"missing archive": {
+			repoURL: "./vendor/test-chart-2.0.0.tgz",
+			err:     "chart archive does not exist",
+		},
+		"outside repository": {
+			repoURL: "file://../vendor",
+			err:     paths.ErrResolvedOutsideRepo.Error(),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pulledChart, err := client.Pull(t.Context(), "test-chart", tc.repoURL, tc.version, repos)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			loadedChart, err := pulledChart.Load(t.Context())
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, loadedChart.Metadata.Version)
 		})
 	}
 }
 
+func TestClientPullLocalFileDependency(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	pkgPath := filepath.Join(root, "pkg")
+	require.NoError(t, os.MkdirAll(pkgPath, 0o700))
+
+	writeLocalChart(t, filepath.Join(root, "charts", "sibling"), "apiVersion: v2\nname: sibling\nversion: 0.1.0\n")
+
+	// Dependencies are relative to the parent chart, not to the package.
+	writeLocalChart(t, filepath.Join(root, "charts", "parent"), "apiVersion: v2\nname: parent\nversion: 0.1.0\n"+
+		"dependencies:\n  - name: sibling\n    version: 0.1.0\n    repository: file://../sibling\n")
+	writeLocalChart(t, filepath.Join(root, "charts", "escape"), "apiVersion: v2\nname: escape\nversion: 0.1.0\n"+
+		"dependencies:\n  - name: sibling\n    version: 0.1.0\n    repository: file://../../../sibling\n")
+
+	repos := helmrepo.NewManager(helmrepo.WithAllowedPaths(pkgPath, root))
+	client := newTestClient(t)
+
+	pulledChart, err := client.Pull(t.Context(), "parent", "../charts", "", repos)
+	require.NoError(t, err)
+
+	loadedChart, err := pulledChart.Load(t.Context())
+	require.NoError(t, err)
+	require.Len(t, loadedChart.Dependencies(), 1)
+	assert.Equal(t, "sibling", loadedChart.Dependencies()[0].Name())
+
+	pulledChart, err = client.Pull(t.Context(), "escape", "../charts", "", repos)
+	require.NoError(t, err)
+
+	_, err = pulledChart.Load(t.Context())
+	require.ErrorIs(t, err, paths.ErrResolvedOutsideRepo)
+}
+
+// writeLocalChart writes a chart directory with the given Chart.yaml to dir.
+func writeLocalChart(t *testing.T, dir, chartYAML string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(dir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte(chartYAML), 0o600))
+}
+
+func TestClientPullDigestRequiresOCI(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+	client := newTestClient(t)
+
+	_, err := client.Pull(
+		t.Context(), "test-chart", srv.URL,
+		"1.2.3@sha256:0b5b3a4d2f7a1e1d6b9c8f1e2d3c4b5a69788796a5b4c3d2e1f0a9b8c7d6e5f4",
+		helmrepo.DefaultManager,
+	)
+	require.ErrorIs(t, err, helm.ErrInvalidDigest)
+}
+
 func TestClientPullDependencyVersions(t *testing.T) {
 	t.Parallel()
 
@@ -209,6 +636,220 @@ func TestClientPullDependencyVersions(t *testing.T) {
 			require.Len(t, deps, 1)
 			assert.Equal(t, "dep-chart", deps[0].Name())
 			assert.Equal(t, tc.want, deps[0].Metadata.Version)
+
+			pulledDeps := pulledChart.Dependencies()
+			require.Len(t, pulledDeps, 1)
+			assert.Equal(t, "dep-chart", pulledDeps[0].Name())
+			assert.Equal(t, srv.URL, pulledDeps[0].RepoURL())
+			assert.Equal(t, tc.want, pulledDeps[0].Version())
+
+			depDigest, err := pulledDeps[0].Digest()
+			require.NoError(t, err)
+			assert.Equal(t, digest.FromBytes(chartArchive(t, "dep-chart", tc.want)).String(), depDigest)
+		})
+	}
+}
+
+func TestClientPullCancel(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	archive := chartArchive(t, "slow-chart", "1.0.0")
+	index := fmt.Sprintf(
+		"apiVersion: v1\nentries:\n  slow-chart:\n    - apiVersion: v2\n      name: slow-chart\n"+
+			"      version: 1.0.0\n      urls:\n        - %s/slow-chart-1.0.0.tgz\n",
+		srv.URL,
+	)
+
+	var once sync.Once
+
+	mux.HandleFunc("/slow-chart-1.0.0.tgz", func(w http.ResponseWriter, _ *http.Request) {
+		once.Do(func() {
+			close(started)
+			<-release
+		})
+
+		_, err := w.Write(archive)
+		assert.NoError(t, err)
+	})
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(index))
+		assert.NoError(t, err)
+	})
+
+	client := newTestClient(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err := client.Pull(ctx, "slow-chart", srv.URL, "1.0.0", helmrepo.DefaultManager)
+	require.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+
+	// The abandoned download must not leave a partial chart in the cache.
+	pulledChart, err := client.Pull(t.Context(), "slow-chart", srv.URL, "1.0.0", helmrepo.DefaultManager)
+	require.NoError(t, err)
+
+	loadedChart, err := pulledChart.Load(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", loadedChart.Metadata.Version)
+}
+
+// newTestKeyring generates a PGP signing key, and writes its public keyring
+// to a file in dir.
+func newTestKeyring(t *testing.T, dir, name string) (*provenance.Signatory, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", nil)
+	require.NoError(t, err)
+
+	var ring bytes.Buffer
+	require.NoError(t, entity.Serialize(&ring))
+
+	keyringPath := filepath.Join(dir, name+".gpg")
+	require.NoError(t, os.WriteFile(keyringPath, ring.Bytes(), 0o600))
+
+	return &provenance.Signatory{Entity: entity}, keyringPath
+}
+
+func TestClientPullVerify(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	signer, keyring := newTestKeyring(t, dir, "trusted")
+	otherSigner, _ := newTestKeyring(t, dir, "untrusted")
+
+	archive := chartArchive(t, "signed-chart", "1.0.0")
+	metadata := []byte("apiVersion: v2\nname: signed-chart\nversion: 1.0.0\n")
+
+	validProv, err := signer.ClearSign(archive, "signed-chart-1.0.0.tgz", metadata)
+	require.NoError(t, err)
+
+	invalidProv, err := otherSigner.ClearSign(archive, "signed-chart-1.0.0.tgz", metadata)
+	require.NoError(t, err)
+
+	newServer := func(prov string) *httptest.Server {
+		mux := http.NewServeMux()
+		srv := httptest.NewServer(mux)
+		t.Cleanup(srv.Close)
+
+		index := fmt.Sprintf(
+			"apiVersion: v1\nentries:\n  signed-chart:\n    - apiVersion: v2\n      name: signed-chart\n"+
+				"      version: 1.0.0\n      urls:\n        - %s/signed-chart-1.0.0.tgz\n",
+			srv.URL,
+		)
+
+		mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, _ *http.Request) {
+			_, err := w.Write([]byte(index))
+			assert.NoError(t, err)
+		})
+		mux.HandleFunc("/signed-chart-1.0.0.tgz", func(w http.ResponseWriter, _ *http.Request) {
+			_, err := w.Write(archive)
+			assert.NoError(t, err)
+		})
+
+		if prov != "" {
+			mux.HandleFunc("/signed-chart-1.0.0.tgz.prov", func(w http.ResponseWriter, _ *http.Request) {
+				_, err := w.Write([]byte(prov))
+				assert.NoError(t, err)
+			})
+		}
+
+		return srv
+	}
+
+	tcs := map[string]struct {
+		prov          string
+		repoVerify    helmrepo.VerifyMode
+		clientVerify  helmrepo.VerifyMode
+		clientKeyring string
+		wantErr       bool
+	}{
+		"always valid": {
+			prov:       validProv,
+			repoVerify: helmrepo.VerifyModeAlways,
+		},
+		"always missing": {
+			repoVerify: helmrepo.VerifyModeAlways,
+			wantErr:    true,
+		},
+		"always invalid": {
+			prov:       invalidProv,
+			repoVerify: helmrepo.VerifyModeAlways,
+			wantErr:    true,
+		},
+		"if possible missing": {
+			repoVerify: helmrepo.VerifyModeIfPossible,
+		},
+		"if possible invalid": {
+			prov:       invalidProv,
+			repoVerify: helmrepo.VerifyModeIfPossible,
+			wantErr:    true,
+		},
+		"never invalid": {
+			prov:       invalidProv,
+			repoVerify: helmrepo.VerifyModeNever,
+		},
+		"never overrides client default": {
+			repoVerify:    helmrepo.VerifyModeNever,
+			clientVerify:  helmrepo.VerifyModeAlways,
+			clientKeyring: keyring,
+		},
+		"client default valid": {
+			prov:          validProv,
+			clientVerify:  helmrepo.VerifyModeAlways,
+			clientKeyring: keyring,
+		},
+		"client default missing": {
+			clientVerify:  helmrepo.VerifyModeAlways,
+			clientKeyring: keyring,
+			wantErr:       true,
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := newServer(tc.prov)
+
+			repoMgr := helmrepo.NewManager(helmrepo.WithAllowedPaths(dir, dir))
+			require.NoError(t, repoMgr.Add(&helmrepo.RepoOpts{
+				Name:    "signed",
+				URL:     srv.URL,
+				Verify:  tc.repoVerify,
+				Keyring: filepath.Base(keyring),
+			}))
+
+			client, err := helm.NewClient(
+				paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()),
+				"test",
+				helm.WithVerify(tc.clientVerify, tc.clientKeyring),
+			)
+			require.NoError(t, err)
+
+			pulledChart, err := client.Pull(t.Context(), "signed-chart", "@signed", "1.0.0", repoMgr)
+			if tc.wantErr {
+				require.ErrorIs(t, err, helm.ErrChartVerify)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			loadedChart, err := pulledChart.Load(t.Context())
+			require.NoError(t, err)
+			assert.Equal(t, "1.0.0", loadedChart.Metadata.Version)
 		})
 	}
 }
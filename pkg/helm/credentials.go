@@ -0,0 +1,164 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/helmpath"
+	"helm.sh/helm/v4/pkg/registry"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+
+	chartrepo "helm.sh/helm/v4/pkg/repo/v1"
+
+	"github.com/macropower/kclipper/pkg/helmrepo"
+)
+
+// WithCredentialStores returns a [ClientOption] that enables or disables
+// reading credentials from Docker's and Helm's config files, see
+// [Client.DisableCredentialStores]. They are enabled by default.
+func WithCredentialStores(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.DisableCredentialStores = !enabled
+	}
+}
+
+// credentialStore holds the credentials that are used for repositories which
+// do not configure their own.
+type credentialStore struct {
+	// registry holds credentials by host, from Helm's registry config and
+	// then Docker's config. Credential helpers configured in either are used.
+	registry credentials.Store
+	// repoFile is the path to Helm's repositories file.
+	repoFile string
+}
+
+// newCredentialStore creates a [credentialStore] from the config files that
+// the Helm and Docker CLIs use, honoring the `HELM_REGISTRY_CONFIG`,
+// `HELM_REPOSITORY_CONFIG` and `DOCKER_CONFIG` environment variables.
+func newCredentialStore() (*credentialStore, error) {
+	opts := credentials.StoreOptions{DetectDefaultNativeStore: true}
+
+	helmStore, err := credentials.NewStore(helmConfigPath("HELM_REGISTRY_CONFIG", "registry", "config.json"), opts)
+	if err != nil {
+		return nil, fmt.Errorf("load helm registry config: %w", err)
+	}
+
+	var store credentials.Store = helmStore
+
+	// This only fails if the home directory cannot be determined, in which
+	// case there is no Docker config to read.
+	dockerStore, err := credentials.NewStoreFromDocker(opts)
+	if err == nil {
+		store = credentials.NewStoreWithFallbacks(helmStore, dockerStore)
+	}
+
+	return &credentialStore{
+		registry: store,
+		repoFile: helmConfigPath("HELM_REPOSITORY_CONFIG", "repositories.yaml"),
+	}, nil
+}
+
+// helmConfigPath returns the path set by the environment variable key, or
+// the given path in Helm's config directory.
+func helmConfigPath(key string, elem ...string) string {
+	if p := os.Getenv(key); p != "" {
+		return p
+	}
+
+	return helmpath.ConfigPath(elem...)
+}
+
+// get returns the username and password for repo. A repository in Helm's
+// repositories file with the same URL takes precedence over credentials
+// stored for the repository's host. Returns empty strings if no credentials
+// are found.
+func (s *credentialStore) get(ctx context.Context, repo *helmrepo.Repo) (string, string) {
+	u, ok := repo.URL.URL()
+	if !ok {
+		return "", ""
+	}
+
+	repoFile, err := chartrepo.LoadFile(s.repoFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.WarnContext(ctx, "read helm repositories file",
+			slog.String("path", s.repoFile),
+			slog.Any("err", err),
+		)
+	}
+
+	if repoFile != nil {
+		for _, e := range repoFile.Repositories {
+			if strings.TrimSuffix(e.URL, "/") == strings.TrimSuffix(u.String(), "/") &&
+				(e.Username != "" || e.Password != "") {
+				return e.Username, e.Password
+			}
+		}
+	}
+
+	cred, err := s.registry.Get(ctx, credentials.ServerAddressFromHostname(u.Host))
+	if err != nil {
+		slog.WarnContext(ctx, "read stored credentials",
+			slog.String("host", u.Host),
+			slog.Any("err", err),
+		)
+
+		return "", ""
+	}
+
+	return cred.Username, cred.Password
+}
+
+// withStoredCredentials returns repo with credentials from the [Client]'s
+// credential stores, if repo is an HTTP(S) repository which does not
+// configure its own. OCI repositories are not changed, since the [Client]'s
+// registry client reads the stores itself. The stores are read on each call,
+// so it must only be called before accessing the repository.
+func (c *Client) withStoredCredentials(ctx context.Context, repo *helmrepo.Repo) *helmrepo.Repo {
+	if c.credentials == nil || repo == nil || repo.IsLocal() || repo.IsGit() || repo.IsOCI() ||
+		repo.Username != "" || repo.Password != "" {
+		return repo
+	}
+
+	username, password := c.credentials.get(ctx, repo)
+	if username == "" && password == "" {
+		return repo
+	}
+
+	slog.DebugContext(ctx, "using stored credentials", slog.String("repo_url", repo.URL.String()))
+
+	withCreds := *repo
+	withCreds.Username = username
+	withCreds.Password = password
+
+	return &withCreds
+}
+
+// registryClientOptions returns the options which configure how the
+// [Client]'s registry client authenticates. With credential stores enabled,
+// the registry client reads the same stores as the [Client]. Otherwise, it
+// is given an authorizer without credentials, since Helm's registry client
+// reads Helm's and Docker's config files by default.
+func (c *Client) registryClientOptions(httpClient *http.Client) []registry.ClientOption {
+	if c.credentials != nil {
+		return []registry.ClientOption{
+			registry.ClientOptCredentialsFile(helmConfigPath("HELM_REGISTRY_CONFIG", "registry", "config.json")),
+		}
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: registry.NewTransport(false)}
+	}
+
+	return []registry.ClientOption{
+		registry.ClientOptAuthorizer(auth.Client{
+			Client: httpClient,
+			Cache:  auth.NewCache(),
+		}),
+	}
+}
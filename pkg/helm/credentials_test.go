@@ -0,0 +1,224 @@
+package helm_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/offline"
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+// Not parallel, since the credential stores are located by environment
+// variables.
+func TestClientPullStoredCredentials(t *testing.T) {
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+	requireBasicAuth(srv, "user", "secret")
+
+	host := mustHost(t, srv.URL)
+	dockerAuth := base64.StdEncoding.EncodeToString([]byte("user:secret"))
+
+	tcs := map[string]struct {
+		dockerConfig string
+		repoConfig   string
+		username     string
+		password     string
+		disabled     bool
+		wantErr      bool
+	}{
+		"docker config": {
+			dockerConfig: fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, host, dockerAuth),
+		},
+		"helm repositories file": {
+			repoConfig: fmt.Sprintf("apiVersion: v1\nrepositories:\n"+
+				"  - name: test\n    url: %s/\n    username: user\n    password: secret\n", srv.URL),
+		},
+		"explicit credentials": {
+			dockerConfig: fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, host, dockerAuth),
+			username:     "user",
+			password:     "wrong",
+			wantErr:      true,
+		},
+		"disabled": {
+			dockerConfig: fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, host, dockerAuth),
+			disabled:     true,
+			wantErr:      true,
+		},
+		"no credentials": {
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			configDir := t.TempDir()
+
+			dockerDir := filepath.Join(configDir, "docker")
+			require.NoError(t, os.MkdirAll(dockerDir, 0o700))
+
+			if tc.dockerConfig != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(dockerDir, "config.json"), []byte(tc.dockerConfig), 0o600))
+			}
+
+			repoConfig := filepath.Join(configDir, "repositories.yaml")
+			if tc.repoConfig != "" {
+				require.NoError(t, os.WriteFile(repoConfig, []byte(tc.repoConfig), 0o600))
+			}
+
+			t.Setenv("DOCKER_CONFIG", dockerDir)
+			t.Setenv("HELM_REGISTRY_CONFIG", filepath.Join(configDir, "registry.json"))
+			t.Setenv("HELM_REPOSITORY_CONFIG", repoConfig)
+
+			client := helm.MustNewClient(
+				paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()), "test",
+				helm.WithCredentialStores(!tc.disabled),
+			)
+
+			repos := helmrepo.NewManager()
+			require.NoError(t, repos.Add(&helmrepo.RepoOpts{
+				Name:     "test",
+				URL:      srv.URL,
+				Username: tc.username,
+				Password: tc.password,
+			}))
+
+			_, err := client.Pull(t.Context(), "test-chart", "@test", "1.2.3", repos)
+			if tc.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+// Not parallel, since the credential stores are located by environment
+// variables.
+func TestClientPullStoredCredentialsRemoteOnly(t *testing.T) {
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+	requireBasicAuth(srv, "user", "secret")
+
+	helperLog := setCredentialHelper(t, mustHost(t, srv.URL))
+	cache := paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder())
+
+	_, err := helm.MustNewClient(cache, "test").Pull(t.Context(), "test-chart", srv.URL, "~1.2.0", helmrepo.DefaultManager)
+	require.NoError(t, err)
+	assert.NotEmpty(t, readCredentialHelperLog(t, helperLog))
+
+	// Cached charts and offline pulls do not access the repository, so its
+	// credentials are not needed.
+	require.NoError(t, os.Truncate(helperLog, 0))
+
+	_, err = helm.MustNewClient(cache, "test").Pull(t.Context(), "test-chart", srv.URL, "1.2.3", helmrepo.DefaultManager)
+	require.NoError(t, err)
+
+	offlineClient := helm.MustNewClient(cache, "test", helm.WithOffline(true))
+
+	_, err = offlineClient.Pull(t.Context(), "test-chart", srv.URL, "1.2.3", helmrepo.DefaultManager)
+	require.NoError(t, err)
+
+	for _, version := range []string{"~1.2.0", "2.0.0"} {
+		_, err = offlineClient.Pull(t.Context(), "test-chart", srv.URL, version, helmrepo.DefaultManager)
+		require.ErrorIs(t, err, offline.ErrOffline)
+	}
+
+	assert.Empty(t, readCredentialHelperLog(t, helperLog))
+}
+
+// Not parallel, since the credential stores are located by environment
+// variables.
+func TestClientPullOCIStoredCredentials(t *testing.T) {
+	srv, _ := newOCIServer(t, "test-chart", []string{"1.2.3"})
+	requireBasicAuth(srv, "user", "secret")
+
+	repoURL := "oci://" + mustHost(t, srv.URL) + "/charts/test-chart"
+
+	tcs := map[string]struct {
+		disabled bool
+	}{
+		"enabled":  {},
+		"disabled": {disabled: true},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			helperLog := setCredentialHelper(t, mustHost(t, srv.URL))
+
+			client := helm.MustNewClient(
+				paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()), "test",
+				helm.WithCredentialStores(!tc.disabled),
+				helm.WithPlainHTTP(true),
+			)
+
+			_, err := client.Pull(t.Context(), "test-chart", repoURL, "1.2.3", helmrepo.DefaultManager)
+			if tc.disabled {
+				require.Error(t, err)
+				assert.Empty(t, readCredentialHelperLog(t, helperLog))
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotEmpty(t, readCredentialHelperLog(t, helperLog))
+		})
+	}
+}
+
+// setCredentialHelper configures Docker's config to read the credentials of
+// host from a credential helper, which returns `user:secret` and logs each
+// call. It returns the path of the log.
+func setCredentialHelper(t *testing.T, host string) string {
+	t.Helper()
+
+	configDir := t.TempDir()
+	helperLog := filepath.Join(configDir, "helper.log")
+
+	helper := fmt.Sprintf("#!/bin/sh\necho \"$1\" >> %q\ncat > /dev/null\n"+
+		"echo '{\"ServerURL\": \"\", \"Username\": \"user\", \"Secret\": \"secret\"}'\n", helperLog)
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "docker-credential-kclippertest"), []byte(helper), 0o700))
+
+	dockerConfig := fmt.Sprintf(`{"credHelpers": {%q: "kclippertest"}}`, host)
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(dockerConfig), 0o600))
+
+	t.Setenv("PATH", configDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("DOCKER_CONFIG", configDir)
+	t.Setenv("HELM_REGISTRY_CONFIG", filepath.Join(configDir, "registry.json"))
+	t.Setenv("HELM_REPOSITORY_CONFIG", filepath.Join(configDir, "repositories.yaml"))
+
+	return helperLog
+}
+
+// readCredentialHelperLog returns the calls logged by the credential helper
+// set by [setCredentialHelper].
+func readCredentialHelperLog(t *testing.T, helperLog string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(helperLog)
+	if errors.Is(err, os.ErrNotExist) {
+		return ""
+	}
+
+	require.NoError(t, err)
+
+	return string(b)
+}
+
+func mustHost(t *testing.T, rawURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+
+	return u.Host
+}
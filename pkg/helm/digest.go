@@ -0,0 +1,106 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	"helm.sh/helm/v4/pkg/registry"
+)
+
+var (
+	// ErrInvalidDigest indicates that a digest in a target revision could not
+	// be parsed.
+	ErrInvalidDigest = errors.New("invalid digest")
+
+	// ErrChartDigestMismatch indicates that a pulled OCI chart did not match
+	// the digest pinned in its target revision.
+	ErrChartDigestMismatch = errors.New("chart digest mismatch")
+)
+
+// ParseTargetRevision splits a target revision into its version and digest.
+// Target revisions may be a version (`1.2.3`), a version pinned to a digest
+// (`1.2.3@sha256:...`), or a bare digest (`sha256:...`). Either return value
+// may be empty.
+func ParseTargetRevision(rev string) (string, string, error) {
+	version, dgst, found := strings.Cut(rev, "@")
+	if !found {
+		// A bare digest always contains an algorithm separator, which is not
+		// valid in a semver version.
+		if !strings.Contains(rev, ":") {
+			return rev, "", nil
+		}
+
+		version, dgst = "", rev
+	}
+
+	d, err := digest.Parse(dgst)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %q: %w", ErrInvalidDigest, dgst, err)
+	}
+
+	return version, d.String(), nil
+}
+
+// pullOCIChartDigest pulls the OCI chart at chartRef by its manifest digest,
+// and writes the chart archive (and its provenance file, if withProv is set
+// and one exists) to dest. If version is set, its tag must resolve to the
+// same digest. Returns the path to the saved chart archive.
+func (c *Client) pullOCIChartDigest(chartRef, version, dgst, dest string, withProv bool) (string, error) {
+	ref := strings.TrimPrefix(chartRef, registry.OCIScheme+"://")
+
+	if version != "" {
+		// OCI tags do not support "+", so Helm replaces it with "_".
+		tag := strings.ReplaceAll(version, "+", "_")
+
+		desc, err := c.rc.Resolve(ref + ":" + tag)
+		if err != nil {
+			return "", fmt.Errorf("resolve %s:%s: %w", ref, tag, err)
+		}
+
+		if desc.Digest.String() != dgst {
+			return "", fmt.Errorf("%w: tag %q resolves to %s, expected %s",
+				ErrChartDigestMismatch, version, desc.Digest, dgst)
+		}
+	}
+
+	result, err := c.rc.Pull(ref+"@"+dgst,
+		registry.PullOptWithProv(withProv),
+		registry.PullOptIgnoreMissingProv(true),
+	)
+	if err != nil {
+		return "", fmt.Errorf("pull %s@%s: %w", ref, dgst, err)
+	}
+
+	if result.Manifest == nil || result.Manifest.Digest != dgst {
+		got := ""
+		if result.Manifest != nil {
+			got = result.Manifest.Digest
+		}
+
+		return "", fmt.Errorf("%w: pulled manifest %s, expected %s", ErrChartDigestMismatch, got, dgst)
+	}
+
+	if result.Chart == nil || result.Chart.Meta == nil {
+		return "", fmt.Errorf("pull %s@%s: no chart found in manifest", ref, dgst)
+	}
+
+	saved := filepath.Join(dest, fmt.Sprintf("%s-%s.tgz", result.Chart.Meta.Name, result.Chart.Meta.Version))
+
+	err = os.WriteFile(saved, result.Chart.Data, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("write chart archive: %w", err)
+	}
+
+	if result.Prov != nil && len(result.Prov.Data) > 0 {
+		err = os.WriteFile(saved+".prov", result.Prov.Data, 0o600)
+		if err != nil {
+			return "", fmt.Errorf("write provenance file: %w", err)
+		}
+	}
+
+	return saved, nil
+}
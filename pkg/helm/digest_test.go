@@ -0,0 +1,228 @@
+package helm_test
+
+import (
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/chartlock"
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/offline"
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+func TestParseTargetRevision(t *testing.T) {
+	t.Parallel()
+
+	const dgst = "sha256:0b5b3a4d2f7a1e1d6b9c8f1e2d3c4b5a69788796a5b4c3d2e1f0a9b8c7d6e5f4"
+
+	tcs := map[string]struct {
+		rev         string
+		wantVersion string
+		wantDigest  string
+		wantErr     error
+	}{
+		"version": {
+			rev:         "1.2.3",
+			wantVersion: "1.2.3",
+		},
+		"range": {
+			rev:         ">=1.2.0 <2.0.0",
+			wantVersion: ">=1.2.0 <2.0.0",
+		},
+		"empty": {},
+		"version and digest": {
+			rev:         "1.2.3@" + dgst,
+			wantVersion: "1.2.3",
+			wantDigest:  dgst,
+		},
+		"bare digest": {
+			rev:        dgst,
+			wantDigest: dgst,
+		},
+		"empty version and digest": {
+			rev:        "@" + dgst,
+			wantDigest: dgst,
+		},
+		"short digest": {
+			rev:     "1.2.3@sha256:0b5b3a4d",
+			wantErr: helm.ErrInvalidDigest,
+		},
+		"unknown algorithm": {
+			rev:     "md5:0b5b3a4d2f7a1e1d6b9c8f1e2d3c4b5a",
+			wantErr: helm.ErrInvalidDigest,
+		},
+		"missing digest": {
+			rev:     "1.2.3@",
+			wantErr: helm.ErrInvalidDigest,
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			version, dgst, err := helm.ParseTargetRevision(tc.rev)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantVersion, version)
+			assert.Equal(t, tc.wantDigest, dgst)
+		})
+	}
+}
+
+func TestClientPullOCIDigest(t *testing.T) {
+	t.Parallel()
+
+	srv, digests := newOCIServer(t, "test-chart", []string{"1.2.3", "1.2.5"})
+	repoURL := "oci://" + mustHost(t, srv.URL) + "/charts/test-chart"
+
+	tcs := map[string]struct {
+		err         error
+		rev         string
+		wantVersion string
+		wantErr     bool
+	}{
+		"version and digest": {
+			rev:         "1.2.3@" + digests["1.2.3"],
+			wantVersion: "1.2.3",
+		},
+		"bare digest": {
+			rev:         digests["1.2.5"],
+			wantVersion: "1.2.5",
+		},
+		"empty version and digest": {
+			rev:         "@" + digests["1.2.3"],
+			wantVersion: "1.2.3",
+		},
+		"tag resolves to another digest": {
+			rev:     "1.2.3@" + digests["1.2.5"],
+			err:     helm.ErrChartDigestMismatch,
+			wantErr: true,
+		},
+		"unknown tag": {
+			rev:     "1.3.0@" + digests["1.2.3"],
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cache := paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder())
+			client := helm.MustNewClient(cache, "test", helm.WithPlainHTTP(true))
+
+			pc, err := client.Pull(t.Context(), "test-chart", repoURL, tc.rev, helmrepo.DefaultManager)
+			if tc.wantErr {
+				require.Error(t, err)
+
+				if tc.err != nil {
+					require.ErrorIs(t, err, tc.err)
+				}
+
+				entries, err := client.CacheEntries(helm.CacheFilter{})
+				require.NoError(t, err)
+				assert.Empty(t, entries)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			loaded, err := pc.Load(t.Context())
+			require.NoError(t, err)
+			require.NoError(t, pc.Close())
+			assert.Equal(t, tc.wantVersion, loaded.Metadata.Version)
+
+			// Charts are cached by digest, so they can be pulled again offline.
+			_, err = helm.MustNewClient(cache, "test", helm.WithOffline(true)).
+				Pull(t.Context(), "test-chart", repoURL, tc.rev, helmrepo.DefaultManager)
+			require.NoError(t, err)
+
+			_, err = helm.MustNewClient(cache, "test", helm.WithOffline(true)).
+				Pull(t.Context(), "test-chart", repoURL, tc.wantVersion, helmrepo.DefaultManager)
+			require.ErrorIs(t, err, offline.ErrOffline)
+		})
+	}
+}
+
+func TestClientPullOCIDigestLock(t *testing.T) {
+	t.Parallel()
+
+	srv, digests := newOCIServer(t, "test-chart", []string{"1.2.3", "1.2.5"})
+	repoURL := "oci://" + mustHost(t, srv.URL) + "/charts/test-chart"
+	lockedDigest := digest.FromBytes(chartArchive(t, "test-chart", "1.2.3")).String()
+
+	tcs := map[string]struct {
+		wantErr     error
+		lockedChart string
+		rev         string
+		wantVersion string
+	}{
+		"bare digest of locked version": {
+			rev:         digests["1.2.3"],
+			wantVersion: "1.2.3",
+		},
+		"version and digest of locked version": {
+			rev:         "1.2.3@" + digests["1.2.3"],
+			wantVersion: "1.2.3",
+		},
+		"bare digest of version not locked": {
+			rev:     digests["1.2.5"],
+			wantErr: helm.ErrChartNotLocked,
+		},
+		"bare digest of chart not locked": {
+			lockedChart: "other-chart",
+			rev:         digests["1.2.5"],
+			wantVersion: "1.2.5",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lockedChart := tc.lockedChart
+			if lockedChart == "" {
+				lockedChart = "test-chart"
+			}
+
+			lock := chartlock.New()
+			lock.Charts["test"] = &chartlock.Chart{
+				Chart:   lockedChart,
+				RepoURL: repoURL,
+				Version: "1.2.3",
+				Digest:  lockedDigest,
+			}
+
+			client := helm.MustNewClient(
+				paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()),
+				"test",
+				helm.WithPlainHTTP(true),
+				helm.WithLock(lock),
+			)
+
+			pc, err := client.Pull(t.Context(), "test-chart", repoURL, tc.rev, helmrepo.DefaultManager)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			loaded, err := pc.Load(t.Context())
+			require.NoError(t, err)
+			require.NoError(t, pc.Close())
+			assert.Equal(t, tc.wantVersion, loaded.Metadata.Version)
+		})
+	}
+}
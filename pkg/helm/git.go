@@ -0,0 +1,342 @@
+package helm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+
+	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/offline"
+)
+
+var (
+	// ErrGitSource indicates that a chart could not be fetched from a Git
+	// repository.
+	ErrGitSource = errors.New("git chart source")
+
+	commitSHARegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// gitSource is a chart location in a Git repository, parsed from a repository
+// URL such as `git+https://host/org/repo.git//charts/foo?ref=v1.2.3`.
+type gitSource struct {
+	// remote is the URL passed to git, without the git+ scheme prefix.
+	remote string
+	// redacted is remote without any password, for use in logs and errors.
+	redacted string
+	// path is the directory within the repository, which is either the chart
+	// itself or a directory containing the chart.
+	path string
+	// ref is the branch, tag or commit to check out. Empty means HEAD.
+	ref string
+}
+
+// parseGitSource parses the URL of a Git [helmrepo.Repo]. The directory within
+// the repository follows a `//` in the URL path, and the ref is given by the
+// `ref` query parameter.
+func parseGitSource(u *url.URL) (*gitSource, error) {
+	scheme, ok := strings.CutPrefix(u.Scheme, "git+")
+	if !ok || scheme == "" {
+		return nil, fmt.Errorf("%w: %q: expected a git+ URL scheme", ErrGitSource, u.Redacted())
+	}
+
+	repoPath, dir, _ := strings.Cut(u.Path, "//")
+
+	dir = filepath.Clean(filepath.FromSlash(dir))
+	if !filepath.IsLocal(dir) {
+		return nil, fmt.Errorf("%w: %q: path must be within the repository", ErrGitSource, u.Redacted())
+	}
+
+	remote := *u
+	remote.Scheme = scheme
+	remote.Path = repoPath
+	remote.RawPath = ""
+	remote.RawQuery = ""
+	remote.Fragment = ""
+
+	return &gitSource{
+		remote:   remote.String(),
+		redacted: remote.Redacted(),
+		path:     dir,
+		ref:      u.Query().Get("ref"),
+	}, nil
+}
+
+// getGitChart sets the path of pc to its chart in the Git repository repo,
+// and its root to the checkout containing it. The repository is checked out
+// into the [PathCacher] at the commit that the ref resolves to, so charts are
+// only fetched again when the ref moves. The repository path may point at the
+// chart directory itself, or at a directory containing it. The checkout stays
+// locked until pc is read, see [PulledChart].
+func (c *Client) getGitChart(ctx context.Context, pc *PulledChart, repo *helmrepo.Repo) error {
+	u, _ := repo.URL.URL()
+
+	src, err := parseGitSource(u)
+	if err != nil {
+		return err
+	}
+
+	sha, err := c.resolveGitRef(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	checkout, lock, err := c.getGitCheckout(ctx, src, sha)
+	if err != nil {
+		return err
+	}
+
+	chartPath, err := gitChartPath(checkout, src.path, pc.chart)
+	if err != nil {
+		_ = lock.Unlock()
+
+		return err
+	}
+
+	pc.path = chartPath
+	pc.root = checkout
+	pc.holdEntry(lock)
+
+	return nil
+}
+
+// gitChartPath returns the path to chart at dir within checkout.
+func gitChartPath(checkout, dir, chart string) (string, error) {
+	chartPath := filepath.Join(checkout, dir)
+
+	exists, err := fileExists(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return "", fmt.Errorf("check chart path: %w", err)
+	}
+
+	if !exists {
+		chartPath = filepath.Join(chartPath, chart)
+	}
+
+	if !dirExists(chartPath) {
+		return "", fmt.Errorf("%w: chart directory does not exist: %q", ErrGitSource, chartPath)
+	}
+
+	return chartPath, nil
+}
+
+// resolveGitRef returns the commit SHA that the ref of src points to. Full
+// commit SHAs are returned as is, without accessing the remote.
+func (c *Client) resolveGitRef(ctx context.Context, src *gitSource) (string, error) {
+	if commitSHARegexp.MatchString(src.ref) {
+		return src.ref, nil
+	}
+
+	ref := src.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	if c.offline() {
+		return "", offline.CacheMissError{
+			Resource: "git ref",
+			Name:     ref,
+			Source:   src.redacted,
+		}
+	}
+
+	// Also list the peeled ref, so that annotated tags resolve to the commit
+	// rather than the tag object.
+	out, err := c.git(ctx, src, "", "ls-remote", "--", src.remote, ref, ref+"^{}")
+	if err != nil {
+		return "", err
+	}
+
+	// ls-remote matches any ref ending in the pattern, e.g. a pattern of `v1`
+	// also matches `refs/heads/release/v1`, so only exact matches are kept.
+	shas := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		lineSHA, name, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue
+		}
+
+		name, peeled := strings.CutSuffix(name, "^{}")
+		if !gitRefMatches(name, ref) {
+			continue
+		}
+
+		if peeled || shas[name] == "" {
+			shas[name] = lineSHA
+		}
+	}
+
+	if len(shas) == 0 {
+		return "", fmt.Errorf("%w: ref %q not found in %q", ErrGitSource, ref, src.redacted)
+	}
+
+	names := slices.Sorted(maps.Keys(shas))
+	if len(names) > 1 {
+		return "", fmt.Errorf("%w: ref %q is ambiguous in %q, matching %s",
+			ErrGitSource, ref, src.redacted, strings.Join(names, ", "))
+	}
+
+	return shas[names[0]], nil
+}
+
+// gitRefMatches returns true if the full ref name refers to ref, i.e. if ref
+// is the full name itself, or the name of a tag or branch.
+func gitRefMatches(name, ref string) bool {
+	return name == ref || name == "refs/tags/"+ref || name == "refs/heads/"+ref
+}
+
+// getGitCheckout returns the path to a checkout of the commit sha of src in
+// the [PathCacher], fetching it if it is not cached. The checkout is returned
+// locked shared, see [lockCacheEntry], and the caller must unlock it once the
+// chart is read.
+func (c *Client) getGitCheckout(ctx context.Context, src *gitSource, sha string) (string, *flock.Flock, error) {
+	// Checkouts hold any number of charts, so the key does not name a chart.
+	checkout, err := c.cachedPath(chartCacheKey{
+		URL:         "git+" + src.redacted,
+		Version:     sha,
+		Project:     c.Project,
+		GitCheckout: true,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("get cached checkout path: %w", err)
+	}
+
+	c.RepoLock.Lock(checkout)
+	defer c.RepoLock.Unlock(checkout)
+
+	lock, exists, err := lockCacheEntry(ctx, checkout)
+	if err != nil {
+		return "", nil, fmt.Errorf("check cached checkout path: %w", err)
+	}
+
+	if exists {
+		now := time.Now()
+
+		err := os.Chtimes(checkout, now, now)
+		if err != nil {
+			slog.DebugContext(ctx, "update cached checkout access time",
+				slog.String("path", checkout),
+				slog.Any("err", err),
+			)
+		}
+
+		return checkout, lock, nil
+	}
+
+	err = c.fetchGitCheckout(ctx, src, sha, checkout)
+	if err == nil {
+		// Downgrade the lock, so that other processes can read the checkout.
+		err = lock.RLock()
+	}
+
+	if err != nil {
+		_ = lock.Unlock()
+
+		return "", nil, err
+	}
+
+	c.pruneCacheAfterPull(ctx, checkout)
+
+	return checkout, lock, nil
+}
+
+// fetchGitCheckout fetches the commit sha of src, and moves its files to
+// checkout.
+func (c *Client) fetchGitCheckout(ctx context.Context, src *gitSource, sha, checkout string) error {
+	if c.offline() {
+		return offline.CacheMissError{
+			Resource: "git commit",
+			Name:     sha,
+			Source:   src.redacted,
+		}
+	}
+
+	slog.InfoContext(ctx, "fetching git repository",
+		slog.String("remote", src.redacted),
+		slog.String("ref", src.ref),
+		slog.String("commit", sha),
+	)
+
+	tempDest, err := os.MkdirTemp("", "kclipper-*")
+	if err != nil {
+		return fmt.Errorf("create temporary checkout directory: %w", err)
+	}
+
+	defer os.RemoveAll(tempDest) //nolint:errcheck // Best-effort cleanup.
+
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"fetch", "--quiet", "--depth=1", "--", src.remote, sha},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		_, err := c.git(ctx, src, tempDest, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Charts are loaded from the checkout like local charts, and must not
+	// include the repository's metadata.
+	err = os.RemoveAll(filepath.Join(tempDest, ".git"))
+	if err != nil {
+		return fmt.Errorf("remove git metadata: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(checkout), 0o700)
+	if err != nil {
+		return fmt.Errorf("create chart cache directory: %w", err)
+	}
+
+	err = os.Rename(tempDest, checkout)
+	if err != nil {
+		return fmt.Errorf("rename checkout from %q to %q: %w", tempDest, checkout, err)
+	}
+
+	return nil
+}
+
+// git runs the git command with args in dir, and returns its standard output.
+// Interactive prompts are disabled, and the [Client]'s proxy is used if set.
+// Credentials in the remote of src are redacted from errors.
+func (c *Client) git(ctx context.Context, src *gitSource, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	if c.Proxy != "" {
+		cmd.Env = append(cmd.Env,
+			"HTTP_PROXY="+c.Proxy,
+			"HTTPS_PROXY="+c.Proxy,
+			"NO_PROXY="+c.NoProxy,
+		)
+	}
+
+	var stderr bytes.Buffer
+
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.ReplaceAll(strings.TrimSpace(stderr.String()), src.remote, src.redacted)
+
+		return nil, fmt.Errorf("%w: git %s: %w: %s", ErrGitSource, args[0], err, msg)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,287 @@
+package helm_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/offline"
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+// gitChartRepo creates a Git repository containing charts/git-chart, which
+// depends on test-chart from depRepoURL. Each version is committed, and
+// tagged as `v<version>`. It returns the repository path and the SHA of each
+// version's commit.
+func gitChartRepo(t *testing.T, depRepoURL string, versions ...string) (string, map[string]string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+
+	git := func(args ...string) string {
+		t.Helper()
+
+		cmd := exec.CommandContext(t.Context(), "git", append([]string{
+			"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main",
+		}, args...)...)
+		cmd.Dir = dir
+
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "--quiet")
+
+	chartDir := filepath.Join(dir, "charts", "git-chart")
+	require.NoError(t, os.MkdirAll(filepath.Join(chartDir, "templates"), 0o700))
+
+	configMap := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, "templates", "configmap.yaml"), []byte(configMap), 0o600))
+
+	shas := map[string]string{}
+
+	for _, version := range versions {
+		chartYAML := fmt.Sprintf("apiVersion: v2\nname: git-chart\nversion: %s\n"+
+			"dependencies:\n  - name: test-chart\n    version: 1.2.3\n    repository: %s\n", version, depRepoURL)
+		require.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chartYAML), 0o600))
+
+		git("add", "-A")
+		git("commit", "--quiet", "-m", "release "+version)
+		git("tag", "-a", "-m", "release "+version, "v"+version)
+
+		shas[version] = git("rev-parse", "HEAD")
+	}
+
+	return dir, shas
+}
+
+func TestClientPullGit(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+	dir, shas := gitChartRepo(t, srv.URL, "1.0.0", "2.0.0")
+
+	// Branches whose names end in a tag's name, which ls-remote lists first,
+	// and a branch with the same name as a tag.
+	for _, args := range [][]string{
+		{"branch", "release/v1.0.0", shas["2.0.0"]},
+		{"tag", "stable", shas["1.0.0"]},
+		{"branch", "old/stable", shas["2.0.0"]},
+		{"branch", "v2.0.0", shas["1.0.0"]},
+	} {
+		out, err := exec.CommandContext(t.Context(), "git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	repos := helmrepo.NewManager(helmrepo.WithAllowedURLSchemes("http", "git+file"))
+	cache := paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder())
+	client := helm.MustNewClient(cache, "test")
+
+	repoURL := "git+file://" + filepath.ToSlash(dir)
+
+	tcs := map[string]struct {
+		err     error
+		chart   string
+		repoURL string
+		want    string
+	}{
+		"chart path with tag": {
+			chart:   "git-chart",
+			repoURL: repoURL + "//charts/git-chart?ref=v1.0.0",
+			want:    "1.0.0",
+		},
+		"parent path with branch": {
+			chart:   "git-chart",
+			repoURL: repoURL + "//charts?ref=main",
+			want:    "2.0.0",
+		},
+		"default ref": {
+			chart:   "git-chart",
+			repoURL: repoURL + "//charts/git-chart",
+			want:    "2.0.0",
+		},
+		"commit sha": {
+			chart:   "git-chart",
+			repoURL: repoURL + "//charts/git-chart?ref=" + shas["1.0.0"],
+			want:    "1.0.0",
+		},
+		"tag with branch of the same suffix": {
+			chart:   "git-chart",
+			repoURL: repoURL + "//charts/git-chart?ref=v1.0.0",
+			want:    "1.0.0",
+		},
+		"lightweight tag with branch of the same suffix": {
+			chart:   "git-chart",
+			repoURL: repoURL + "//charts/git-chart?ref=stable",
+			want:    "1.0.0",
+		},
+		"full branch name": {
+			chart:   "git-chart",
+			repoURL: repoURL + "//charts/git-chart?ref=refs/heads/release/v1.0.0",
+			want:    "2.0.0",
+		},
+		"ambiguous ref": {
+			chart:   "git-chart",
+			repoURL: repoURL + "//charts/git-chart?ref=v2.0.0",
+			err:     helm.ErrGitSource,
+		},
+		"unknown ref": {
+			chart:   "git-chart",
+			repoURL: repoURL + "//charts/git-chart?ref=v3.0.0",
+			err:     helm.ErrGitSource,
+		},
+		"unknown chart": {
+			chart:   "other-chart",
+			repoURL: repoURL + "//charts?ref=v1.0.0",
+			err:     helm.ErrGitSource,
+		},
+		"path outside repository": {
+			chart:   "git-chart",
+			repoURL: repoURL + "//../charts?ref=v1.0.0",
+			err:     helm.ErrGitSource,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pc, err := client.Pull(t.Context(), tc.chart, tc.repoURL, "", repos)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			loaded, err := pc.Load(t.Context())
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, pc.Version())
+			assert.Len(t, loaded.Dependencies(), 1)
+		})
+	}
+}
+
+func TestClientPullGitFileDependency(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+	dir, _ := gitChartRepo(t, srv.URL, "1.0.0")
+
+	// The dependency is relative to the parent chart within the checkout.
+	writeLocalChart(t, filepath.Join(dir, "charts", "git-parent"), "apiVersion: v2\nname: git-parent\nversion: 0.1.0\n"+
+		"dependencies:\n  - name: git-chart\n    version: 1.0.0\n    repository: file://../git-chart\n")
+	writeLocalChart(t, filepath.Join(dir, "charts", "git-escape"), "apiVersion: v2\nname: git-escape\nversion: 0.1.0\n"+
+		"dependencies:\n  - name: git-chart\n    version: 1.0.0\n    repository: file://../../../git-chart\n")
+
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "add git-parent"},
+	} {
+		out, err := exec.CommandContext(t.Context(), "git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	repos := helmrepo.NewManager(helmrepo.WithAllowedURLSchemes("http", "git+file"))
+	client := helm.MustNewClient(paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()), "test")
+
+	repoURL := "git+file://" + filepath.ToSlash(dir) + "//charts"
+
+	pc, err := client.Pull(t.Context(), "git-parent", repoURL, "", repos)
+	require.NoError(t, err)
+
+	loaded, err := pc.Load(t.Context())
+	require.NoError(t, err)
+	require.Len(t, loaded.Dependencies(), 1)
+
+	dep := loaded.Dependencies()[0]
+	assert.Equal(t, "git-chart", dep.Name())
+	assert.Equal(t, "1.0.0", dep.Metadata.Version)
+	require.Len(t, dep.Dependencies(), 1)
+
+	pc, err = client.Pull(t.Context(), "git-escape", repoURL, "", repos)
+	require.NoError(t, err)
+
+	_, err = pc.Load(t.Context())
+	require.ErrorIs(t, err, paths.ErrResolvedOutsideRepo)
+}
+
+func TestClientPullGitOffline(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+	dir, shas := gitChartRepo(t, srv.URL, "1.0.0")
+
+	repos := helmrepo.NewManager(helmrepo.WithAllowedURLSchemes("http", "git+file"))
+	cache := paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder())
+
+	repoURL := "git+file://" + filepath.ToSlash(dir) + "//charts/git-chart"
+
+	_, err := helm.MustNewClient(cache, "test").Pull(t.Context(), "git-chart", repoURL+"?ref=v1.0.0", "", repos)
+	require.NoError(t, err)
+
+	// Checkouts are cached by commit, so pinned commits can be pulled offline,
+	// but refs cannot be resolved.
+	client := helm.MustNewClient(cache, "test", helm.WithOffline(true))
+
+	_, err = client.Pull(t.Context(), "git-chart", repoURL+"?ref="+shas["1.0.0"], "", repos)
+	require.NoError(t, err)
+
+	_, err = client.Pull(t.Context(), "git-chart", repoURL+"?ref=v1.0.0", "", repos)
+	require.ErrorIs(t, err, offline.ErrOffline)
+}
+
+func TestClientGitCheckoutCacheEntries(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+	dir, shas := gitChartRepo(t, srv.URL, "1.0.0")
+
+	repos := helmrepo.NewManager(helmrepo.WithAllowedURLSchemes("http", "git+file"))
+	cache := paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder())
+	client := helm.MustNewClient(cache, "test")
+
+	remote := "git+file://" + filepath.ToSlash(dir)
+
+	pc, err := client.Pull(t.Context(), "git-chart", remote+"//charts/git-chart?ref=v1.0.0", "", repos)
+	require.NoError(t, err)
+	require.NoError(t, pc.Close())
+
+	entries, err := client.CacheEntries(helm.CacheFilter{RepoURL: remote})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	checkout := entries[0]
+	assert.True(t, checkout.GitCheckout)
+	assert.Empty(t, checkout.Chart)
+	assert.Equal(t, shas["1.0.0"], checkout.Version)
+	assert.Equal(t, "test", checkout.Project)
+	assert.Positive(t, checkout.Size)
+	require.NoError(t, client.VerifyCacheEntry(checkout))
+
+	old := time.Now().Add(-24 * time.Hour)
+	require.NoError(t, os.Chtimes(checkout.Path, old, old))
+
+	evicted, err := helm.MustNewClient(cache, "test", helm.WithCacheLimits(0, time.Hour)).PruneCache(t.Context())
+	require.NoError(t, err)
+	require.Len(t, evicted, 1)
+	assert.Equal(t, checkout.Path, evicted[0].Path)
+	assert.NoDirExists(t, checkout.Path)
+}
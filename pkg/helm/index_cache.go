@@ -0,0 +1,370 @@
+package helm
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	chartrepo "helm.sh/helm/v4/pkg/repo/v1"
+
+	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/syncs"
+)
+
+// DefaultIndexCacheTTL is the default time for which a cached repository
+// index is used without revalidating it.
+const DefaultIndexCacheTTL = 5 * time.Minute
+
+// ErrIndexDownload indicates that a repository index could not be downloaded.
+var ErrIndexDownload = errors.New("download repository index")
+
+// IndexCache stores the index.yaml files of HTTP(S) chart repositories on
+// disk, and keeps parsed indexes in memory, so that resolving and pulling
+// charts from the same repository does not repeatedly download and parse its
+// index. Create instances with [NewIndexCache].
+//
+// Cached indexes are used without network access until they are older than
+// the TTL. After that, they are revalidated with a conditional request
+// (If-None-Match/If-Modified-Since), and only downloaded again if the
+// repository reports that the index has changed.
+type IndexCache struct {
+	entries map[string]*indexCacheEntry
+	lock    *syncs.KeyLock
+	dir     string
+	ttl     time.Duration
+	mu      sync.Mutex
+}
+
+// NewIndexCache creates a new [IndexCache] which stores indexes in dir, and
+// uses them without revalidation for ttl. A ttl of zero revalidates indexes
+// on every use. The directory is created when the first index is stored.
+func NewIndexCache(dir string, ttl time.Duration) *IndexCache {
+	return &IndexCache{
+		entries: map[string]*indexCacheEntry{},
+		lock:    syncs.NewKeyLock(),
+		dir:     dir,
+		ttl:     max(ttl, 0),
+	}
+}
+
+// WithIndexCache returns a [ClientOption] that sets the [IndexCache] used for
+// HTTP(S) repositories. By default, each [Client] keeps an in-memory cache
+// which is revalidated on every use.
+func WithIndexCache(ic *IndexCache) ClientOption {
+	return func(c *Client) {
+		c.IndexCache = ic
+	}
+}
+
+// repoIndex returns the index of the HTTP(S) repository repo from the
+// [Client]'s [IndexCache].
+func (c *Client) repoIndex(ctx context.Context, repo *helmrepo.Repo) (*chartrepo.IndexFile, error) {
+	client, err := c.httpClient(
+		repo.TLSClientCertDataPath.String(),
+		repo.TLSClientCertKeyPath.String(),
+		repo.CAPath.String(),
+		repo.InsecureSkipVerify,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create http client: %w", err)
+	}
+
+	return c.IndexCache.Index(ctx, client, repo)
+}
+
+// findChartInIndex returns the absolute URL of the given version of chart in
+// the index of the HTTP(S) repository repo.
+func (c *Client) findChartInIndex(ctx context.Context, chart, version string, repo *helmrepo.Repo) (string, error) {
+	index, err := c.repoIndex(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+
+	repoURL := repo.URL.String()
+
+	cv, err := index.Get(chart, version)
+	if err != nil {
+		return "", chartrepo.ChartNotFoundError{
+			Chart:   fmt.Sprintf("chart %q version %q", chart, version),
+			RepoURL: repoURL,
+		}
+	}
+
+	if len(cv.URLs) == 0 {
+		return "", fmt.Errorf("chart %q version %q has no downloadable URLs", chart, version)
+	}
+
+	chartURL, err := chartrepo.ResolveReferenceURL(repoURL, cv.URLs[0])
+	if err != nil {
+		return "", fmt.Errorf("make chart URL absolute: %w", err)
+	}
+
+	return chartURL, nil
+}
+
+// indexCacheKey identifies a repository index in an [IndexCache]. The
+// credentials are included, since repositories may serve different indexes to
+// different users, including users which only authenticate with a token.
+type indexCacheKey struct {
+	URL      string `json:"url"`
+	Username string `json:"username,omitempty"`
+	// Credentials is the hash of the username and password, see
+	// [credentialsHash].
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// credentialsHash returns the hex-encoded sha256 hash of the credentials of
+// repo, or an empty string if it has none.
+func credentialsHash(repo *helmrepo.Repo) string {
+	if repo.Username == "" && repo.Password == "" {
+		return ""
+	}
+
+	// The username is also part of the key, so the pair is unambiguous.
+	sum := sha256.Sum256([]byte(repo.Username + ":" + repo.Password))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// indexCacheEntry is the metadata of a cached repository index, which is
+// stored next to the index file.
+type indexCacheEntry struct {
+	// index is the parsed index, if it was loaded by this process.
+	index *chartrepo.IndexFile
+	// Fetched is the last time the index was downloaded or revalidated.
+	Fetched      time.Time `json:"fetched"`
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+}
+
+// Index returns the parsed index of repo, using client to download or
+// revalidate it when needed.
+func (ic *IndexCache) Index(ctx context.Context, client *http.Client, repo *helmrepo.Repo) (*chartrepo.IndexFile, error) {
+	repoURL := repo.URL.String()
+
+	keyData, err := json.Marshal(indexCacheKey{
+		URL:         repoURL,
+		Username:    repo.Username,
+		Credentials: credentialsHash(repo),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal index cache key: %w", err)
+	}
+
+	sum := sha256.Sum256(keyData)
+	key := hex.EncodeToString(sum[:])
+
+	ic.lock.Lock(key)
+	defer ic.lock.Unlock(key)
+
+	entry := ic.load(ctx, key)
+	if entry != nil && time.Since(entry.Fetched) < ic.ttl {
+		index, err := ic.parse(key, entry)
+		if err == nil {
+			return index, nil
+		}
+
+		// Download the index again, rather than failing on a corrupt entry.
+		slog.DebugContext(ctx, "load cached repository index",
+			slog.String("repo_url", repoURL),
+			slog.Any("err", err),
+		)
+
+		entry = nil
+	}
+
+	entry, err = ic.fetch(ctx, client, repo, key, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return ic.parse(key, entry)
+}
+
+// load returns the entry for key from memory, or from disk if it was stored by
+// another process. It returns nil if there is no usable entry.
+func (ic *IndexCache) load(ctx context.Context, key string) *indexCacheEntry {
+	ic.mu.Lock()
+	entry, ok := ic.entries[key]
+	ic.mu.Unlock()
+
+	if ok {
+		return entry
+	}
+
+	data, err := os.ReadFile(ic.path(key, ".json"))
+	if err != nil {
+		return nil
+	}
+
+	// The index file may have been removed without its metadata.
+	_, err = os.Stat(ic.path(key, ".yaml"))
+	if err != nil {
+		return nil
+	}
+
+	entry = &indexCacheEntry{}
+
+	err = json.Unmarshal(data, entry)
+	if err != nil {
+		slog.DebugContext(ctx, "decode cached repository index metadata",
+			slog.String("path", ic.path(key, ".json")),
+			slog.Any("err", err),
+		)
+
+		return nil
+	}
+
+	return entry
+}
+
+// parse returns the parsed index of entry, loading it from disk if needed.
+func (ic *IndexCache) parse(key string, entry *indexCacheEntry) (*chartrepo.IndexFile, error) {
+	if entry.index == nil {
+		index, err := chartrepo.LoadIndexFile(ic.path(key, ".yaml"))
+		if err != nil {
+			return nil, fmt.Errorf("load index for %q: %w", entry.URL, err)
+		}
+
+		entry.index = index
+	}
+
+	ic.mu.Lock()
+	ic.entries[key] = entry
+	ic.mu.Unlock()
+
+	return entry.index, nil
+}
+
+// fetch downloads the index of repo. If cached is not nil, the request is
+// made conditional on the cached index having changed.
+func (ic *IndexCache) fetch(
+	ctx context.Context,
+	client *http.Client,
+	repo *helmrepo.Repo,
+	key string,
+	cached *indexCacheEntry,
+) (*indexCacheEntry, error) {
+	repoURL := repo.URL.String()
+
+	indexURL, err := chartrepo.ResolveReferenceURL(repoURL, "index.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIndexDownload, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, indexURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIndexDownload, err)
+	}
+
+	if repo.Username != "" || repo.Password != "" {
+		req.SetBasicAuth(repo.Username, repo.Password)
+	}
+
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrIndexDownload, repoURL, err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck // Best-effort close.
+
+	entry := &indexCacheEntry{
+		URL:          repoURL,
+		Fetched:      time.Now(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		slog.DebugContext(ctx, "repository index not modified", slog.String("repo_url", repoURL))
+
+		entry.index = cached.index
+		entry.ETag = cmp.Or(entry.ETag, cached.ETag)
+		entry.LastModified = cmp.Or(entry.LastModified, cached.LastModified)
+
+	case resp.StatusCode == http.StatusOK:
+		slog.DebugContext(ctx, "downloading repository index", slog.String("repo_url", repoURL))
+
+		err = ic.write(key, ".yaml", resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: %q: %s", ErrIndexDownload, repoURL, resp.Status)
+	}
+
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("marshal index cache metadata: %w", err)
+	}
+
+	err = ic.write(key, ".json", bytes.NewReader(meta))
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (ic *IndexCache) path(key, ext string) string {
+	return filepath.Join(ic.dir, key+ext)
+}
+
+// write stores the content of r in the file for key and ext. The content is
+// written to a temporary file first, so that concurrent readers (including
+// other processes) never see a partial file.
+func (ic *IndexCache) write(key, ext string, r io.Reader) error {
+	err := os.MkdirAll(ic.dir, 0o700)
+	if err != nil {
+		return fmt.Errorf("create index cache directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(ic.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create index cache entry: %w", err)
+	}
+
+	_, err = io.Copy(f, r)
+	if err == nil {
+		err = f.Close()
+	} else {
+		_ = f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), ic.path(key, ext))
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+
+		return fmt.Errorf("write index cache entry: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package helm_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+func TestClientIndexCache(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		versions = []string{"1.2.3", "1.2.5"}
+		statuses = []int{}
+	)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	for _, version := range []string{"1.2.3", "1.2.5", "1.3.0"} {
+		archive := chartArchive(t, "test-chart", version)
+		mux.HandleFunc(fmt.Sprintf("/test-chart-%s.tgz", version),
+			func(w http.ResponseWriter, _ *http.Request) {
+				_, err := w.Write(archive)
+				assert.NoError(t, err)
+			})
+	}
+
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var index strings.Builder
+
+		index.WriteString("apiVersion: v1\nentries:\n  test-chart:\n")
+
+		for _, version := range versions {
+			fmt.Fprintf(&index, "    - apiVersion: v2\n      name: test-chart\n      version: %s\n", version)
+			fmt.Fprintf(&index, "      urls:\n        - test-chart-%s.tgz\n", version)
+		}
+
+		rec := httptest.NewRecorder()
+		rec.Header().Set("ETag", fmt.Sprintf("%q", strings.Join(versions, ",")))
+		http.ServeContent(rec, r, "index.yaml", time.Time{}, strings.NewReader(index.String()))
+
+		statuses = append(statuses, rec.Code)
+
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+
+		w.WriteHeader(rec.Code)
+
+		_, err := w.Write(rec.Body.Bytes())
+		assert.NoError(t, err)
+	})
+
+	requests := func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]int{}, statuses...)
+	}
+
+	cache := paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder())
+	cacheDir := t.TempDir()
+
+	// Resolving and pulling charts within the TTL downloads the index once.
+	client := helm.MustNewClient(cache, "test", helm.WithIndexCache(helm.NewIndexCache(cacheDir, time.Hour)))
+
+	for _, version := range []string{"1.2.3", "~1.2.0"} {
+		pc, err := client.Pull(t.Context(), "test-chart", srv.URL, version, helmrepo.DefaultManager)
+		require.NoError(t, err)
+		assert.Contains(t, []string{"1.2.3", "1.2.5"}, pc.Version())
+	}
+
+	assert.Equal(t, []int{http.StatusOK}, requests())
+
+	// The persisted index is revalidated once the TTL has passed.
+	client = helm.MustNewClient(cache, "test", helm.WithIndexCache(helm.NewIndexCache(cacheDir, 0)))
+
+	_, err := client.Pull(t.Context(), "test-chart", srv.URL, "~1.2.0", helmrepo.DefaultManager)
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotModified}, requests())
+
+	// Changed indexes are downloaded again. Pulling the resolved version
+	// revalidates the index a second time, since the TTL is zero.
+	mu.Lock()
+	versions = append(versions, "1.3.0")
+	mu.Unlock()
+
+	pc, err := client.Pull(t.Context(), "test-chart", srv.URL, "~1.2.0 || ~1.3.0", helmrepo.DefaultManager)
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.0", pc.Version())
+
+	assert.Equal(t, []int{
+		http.StatusOK,
+		http.StatusNotModified,
+		http.StatusOK,
+		http.StatusNotModified,
+	}, requests())
+}
+
+func TestClientIndexCacheCredentials(t *testing.T) {
+	t.Parallel()
+
+	// Each token sees a different version of the chart.
+	tokens := map[string]string{"token-a": "1.2.3", "token-b": "1.2.5"}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	for _, version := range tokens {
+		archive := chartArchive(t, "test-chart", version)
+		mux.HandleFunc(fmt.Sprintf("/test-chart-%s.tgz", version),
+			func(w http.ResponseWriter, _ *http.Request) {
+				_, err := w.Write(archive)
+				assert.NoError(t, err)
+			})
+	}
+
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		_, token, _ := r.BasicAuth()
+
+		version, ok := tokens[token]
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		_, err := fmt.Fprintf(w, "apiVersion: v1\nentries:\n  test-chart:\n"+
+			"    - apiVersion: v2\n      name: test-chart\n      version: %s\n"+
+			"      urls:\n        - test-chart-%s.tgz\n", version, version)
+		assert.NoError(t, err)
+	})
+
+	indexCache := helm.NewIndexCache(t.TempDir(), time.Hour)
+
+	// Tenants which only authenticate with a token do not share an index.
+	for _, token := range []string{"token-a", "token-b", "token-a"} {
+		repos := helmrepo.NewManager()
+		require.NoError(t, repos.Add(&helmrepo.RepoOpts{Name: "repo", URL: srv.URL, Password: token}))
+
+		client := helm.MustNewClient(
+			paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()), "test",
+			helm.WithIndexCache(indexCache),
+		)
+
+		pc, err := client.Pull(t.Context(), "test-chart", "@repo", "~1.2.0", repos)
+		require.NoError(t, err)
+		assert.Equal(t, tokens[token], pc.Version())
+		require.NoError(t, pc.Close())
+	}
+}
+
+func TestClientIndexCacheConnectionReuse(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		remotes = map[string]int{}
+	)
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+
+	next := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index.yaml" {
+			mu.Lock()
+			remotes[r.RemoteAddr]++
+			mu.Unlock()
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	client := helm.MustNewClient(
+		paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()), "test",
+		helm.WithIndexCache(helm.NewIndexCache(t.TempDir(), 0)),
+	)
+
+	for range 3 {
+		pc, err := client.Pull(t.Context(), "test-chart", srv.URL, "~1.2.0", helmrepo.DefaultManager)
+		require.NoError(t, err)
+		require.NoError(t, pc.Close())
+	}
+
+	// The index is fetched for each pull, over the same connection.
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, remotes, 1)
+
+	for _, n := range remotes {
+		assert.GreaterOrEqual(t, n, 3)
+	}
+}
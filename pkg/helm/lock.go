@@ -0,0 +1,112 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+)
+
+var (
+	// ErrChartLockMismatch indicates that a pulled chart did not match the
+	// digest recorded for it by the [Client]'s [ChartLocker].
+	ErrChartLockMismatch = errors.New("chart does not match lock")
+
+	// ErrChartNotLocked indicates that a chart is locked by the [Client]'s
+	// [ChartLocker], but not at the version that was pulled.
+	ErrChartNotLocked = errors.New("chart version not locked")
+)
+
+// ChartLocker looks up the versions and digests of locked charts.
+// See [chartlock.Lock] for an implementation.
+type ChartLocker interface {
+	LockedVersion(chart, repoURL, constraint string) (string, bool)
+	LockedVersions(chart, repoURL string) []string
+	LockedDigests(chart, repoURL string) []string
+	LockedDigest(chart, repoURL, version string) (string, bool)
+}
+
+// WithLock returns a [ClientOption] that pins version constraints to the
+// versions locked by locker, and verifies that pulled charts match their
+// locked digests. Charts which are locked at other versions are rejected with
+// [ErrChartNotLocked]. Charts which are pinned only by a digest are checked
+// against all locked digests of the chart instead. Charts which are not locked
+// are pulled as usual.
+func WithLock(locker ChartLocker) ClientOption {
+	return func(c *Client) {
+		c.Lock = locker
+	}
+}
+
+// verifyLock checks that version is locked, if chart is locked at all, and
+// that the chart archive at chartPath matches its locked digest, if there is
+// one. If version is empty, i.e. the chart was pinned only by a digest, the
+// archive must match one of the chart's locked digests instead.
+func (c *Client) verifyLock(chart, repo, version, chartPath string) error {
+	if c.Lock == nil {
+		return nil
+	}
+
+	locked := c.Lock.LockedVersions(chart, repo)
+	if version == "" && len(locked) > 0 {
+		return c.verifyLockDigest(chart, repo, chartPath)
+	}
+	if len(locked) > 0 && !slices.Contains(locked, version) {
+		return fmt.Errorf("%w: %s %s from %q, locked versions are %s",
+			ErrChartNotLocked, chart, version, repo, strings.Join(locked, ", "))
+	}
+
+	want, ok := c.Lock.LockedDigest(chart, repo, version)
+	if !ok {
+		return nil
+	}
+
+	got, err := archiveDigest(chartPath)
+	if err != nil {
+		return err
+	}
+
+	if got != want {
+		return fmt.Errorf("%w: %s %s from %q has digest %s, expected %s",
+			ErrChartLockMismatch, chart, version, repo, got, want)
+	}
+
+	return nil
+}
+
+// verifyLockDigest checks that the chart archive at chartPath matches one of
+// the locked digests of chart. Since the archive's version is not known, a
+// chart which is locked without any digests is rejected.
+func (c *Client) verifyLockDigest(chart, repo, chartPath string) error {
+	got, err := archiveDigest(chartPath)
+	if err != nil {
+		return err
+	}
+
+	locked := c.Lock.LockedDigests(chart, repo)
+	if !slices.Contains(locked, got) {
+		return fmt.Errorf("%w: %s with digest %s from %q, locked digests are %s",
+			ErrChartNotLocked, chart, got, repo, strings.Join(locked, ", "))
+	}
+
+	return nil
+}
+
+// archiveDigest returns the sha256 digest of the chart archive at path.
+func archiveDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open chart archive: %w", err)
+	}
+	defer f.Close() //nolint:errcheck // Best-effort close.
+
+	d, err := digest.SHA256.FromReader(f)
+	if err != nil {
+		return "", fmt.Errorf("digest chart archive: %w", err)
+	}
+
+	return d.String(), nil
+}
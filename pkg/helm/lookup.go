@@ -0,0 +1,79 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/macropower/kclipper/pkg/kube"
+)
+
+// ErrInvalidLookup indicates that an object provided for Helm's `lookup`
+// function is missing required fields.
+var ErrInvalidLookup = errors.New("invalid lookup object")
+
+// lookupClient is a fake Kubernetes client which serves a fixed set of
+// objects to Helm's `lookup` template function, in place of a cluster.
+type lookupClient struct {
+	objs []kube.Object
+}
+
+// newLookupClient creates a new [lookupClient] serving objs. Each object must
+// have an apiVersion, kind, and metadata.name.
+func newLookupClient(objs []kube.Object) (*lookupClient, error) {
+	var merr error
+
+	for i, obj := range objs {
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" || obj.GetName() == "" {
+			merr = errors.Join(merr, fmt.Errorf("%w at index %d: apiVersion, kind, and metadata.name are required",
+				ErrInvalidLookup, i))
+		}
+	}
+
+	if merr != nil {
+		return nil, merr
+	}
+
+	return &lookupClient{objs: objs}, nil
+}
+
+// Lookup implements Helm's `lookup` template function. If name is set, the
+// matching object is returned, or an empty map if there is no match.
+// Otherwise, a list of all matching objects is returned. An empty namespace
+// matches objects in all namespaces, and objects without a namespace (i.e.
+// cluster-scoped objects) match any namespace.
+func (c *lookupClient) Lookup(apiVersion, kind, namespace, name string) (map[string]any, error) {
+	if name != "" {
+		for _, obj := range c.objs {
+			if c.matches(obj, apiVersion, kind, namespace) && obj.GetName() == name {
+				return obj.DeepCopy(), nil
+			}
+		}
+
+		return map[string]any{}, nil
+	}
+
+	items := []any{}
+
+	for _, obj := range c.objs {
+		if c.matches(obj, apiVersion, kind, namespace) {
+			items = append(items, map[string]any(obj.DeepCopy()))
+		}
+	}
+
+	return map[string]any{
+		"apiVersion": apiVersion,
+		"kind":       kind + "List",
+		"metadata":   map[string]any{},
+		"items":      items,
+	}, nil
+}
+
+func (c *lookupClient) matches(obj kube.Object, apiVersion, kind, namespace string) bool {
+	if obj.GetAPIVersion() != apiVersion || obj.GetKind() != kind {
+		return false
+	}
+
+	objNamespace := obj.GetNamespace()
+
+	return namespace == "" || objNamespace == "" || objNamespace == namespace
+}
@@ -0,0 +1,240 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/macropower/kclipper/pkg/kube"
+)
+
+// ArgoCDTrackingIDAnnotation is the annotation that Argo CD uses to track the
+// resources of an application, when annotation-based tracking is enabled.
+const ArgoCDTrackingIDAnnotation = "argocd.argoproj.io/tracking-id"
+
+// ErrPostRender indicates that the built-in post-renderers configured in
+// [TemplateOpts] could not be applied.
+var ErrPostRender = errors.New("post-render")
+
+// clusterScopedKinds are the built-in cluster-scoped kinds, keyed by group and
+// kind. Kinds which are not listed are assumed to be namespaced, unless a
+// cluster-scoped CRD for them is rendered alongside them, or they are listed
+// in [TemplateOpts.ClusterScopedKinds].
+var clusterScopedKinds = map[string]bool{
+	"Namespace":        true,
+	"Node":             true,
+	"PersistentVolume": true,
+	"ComponentStatus":  true,
+
+	"APIService.apiregistration.k8s.io":                             true,
+	"CertificateSigningRequest.certificates.k8s.io":                 true,
+	"ClusterTrustBundle.certificates.k8s.io":                        true,
+	"CustomResourceDefinition.apiextensions.k8s.io":                 true,
+	"MutatingWebhookConfiguration.admissionregistration.k8s.io":     true,
+	"ValidatingWebhookConfiguration.admissionregistration.k8s.io":   true,
+	"MutatingAdmissionPolicy.admissionregistration.k8s.io":          true,
+	"MutatingAdmissionPolicyBinding.admissionregistration.k8s.io":   true,
+	"ValidatingAdmissionPolicy.admissionregistration.k8s.io":        true,
+	"ValidatingAdmissionPolicyBinding.admissionregistration.k8s.io": true,
+	"ClusterRole.rbac.authorization.k8s.io":                         true,
+	"ClusterRoleBinding.rbac.authorization.k8s.io":                  true,
+	"PriorityClass.scheduling.k8s.io":                               true,
+	"RuntimeClass.node.k8s.io":                                      true,
+	"IngressClass.networking.k8s.io":                                true,
+	"IPAddress.networking.k8s.io":                                   true,
+	"ServiceCIDR.networking.k8s.io":                                 true,
+	"StorageClass.storage.k8s.io":                                   true,
+	"CSIDriver.storage.k8s.io":                                      true,
+	"CSINode.storage.k8s.io":                                        true,
+	"VolumeAttachment.storage.k8s.io":                               true,
+	"VolumeAttributesClass.storage.k8s.io":                          true,
+	"DeviceClass.resource.k8s.io":                                   true,
+	"FlowSchema.flowcontrol.apiserver.k8s.io":                       true,
+	"PriorityLevelConfiguration.flowcontrol.apiserver.k8s.io":       true,
+	"PodSecurityPolicy.policy":                                      true,
+	"StorageVersionMigration.storagemigration.k8s.io":               true,
+	"ResourceSlice.resource.k8s.io":                                 true,
+}
+
+// hasPostRender returns true if any built-in post-renderers are configured.
+func (t *TemplateOpts) hasPostRender() bool {
+	return len(t.CommonLabels) > 0 || len(t.CommonAnnotations) > 0 ||
+		t.ForceNamespace || t.CreateNamespace || t.ArgoCDAppName != ""
+}
+
+// postRenderer applies the built-in post-renderers configured in
+// [TemplateOpts] to rendered resources. Create instances with
+// [newPostRenderer].
+type postRenderer struct {
+	opts *TemplateOpts
+	// customClusterScoped holds the group and kind of cluster-scoped custom
+	// resources, which are defined by rendered CRDs or listed in
+	// [TemplateOpts.ClusterScopedKinds].
+	customClusterScoped map[string]bool
+}
+
+// newPostRenderer creates a new [postRenderer] for resources rendered with
+// opts. CRDs in objs and [TemplateOpts.ClusterScopedKinds] are used to
+// determine which custom resources are cluster-scoped.
+func newPostRenderer(objs []kube.Object, opts *TemplateOpts) (*postRenderer, error) {
+	if (opts.ForceNamespace || opts.CreateNamespace) && opts.Namespace == "" {
+		return nil, fmt.Errorf("%w: a namespace is required to force or create it", ErrPostRender)
+	}
+
+	pr := &postRenderer{
+		opts:                opts,
+		customClusterScoped: map[string]bool{},
+	}
+
+	for _, gk := range opts.ClusterScopedKinds {
+		pr.customClusterScoped[gk] = true
+	}
+
+	for _, obj := range objs {
+		if !obj.IsCRD() {
+			continue
+		}
+
+		spec, _ := obj["spec"].(map[string]any)
+		names, _ := spec["names"].(map[string]any)
+
+		scope, _ := spec["scope"].(string)
+		group, _ := spec["group"].(string)
+		kind, _ := names["kind"].(string)
+
+		if scope != "Cluster" || kind == "" {
+			continue
+		}
+
+		pr.customClusterScoped[groupKind(group, kind)] = true
+	}
+
+	return pr, nil
+}
+
+// postRender applies the built-in post-renderers configured in t to objs, and
+// returns the result. See [postRenderer.render].
+func postRender(objs []kube.Object, t *TemplateOpts) ([]kube.Object, error) {
+	if !t.hasPostRender() {
+		return objs, nil
+	}
+
+	pr, err := newPostRenderer(objs, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return pr.render(objs), nil
+}
+
+// render applies the post-renderers to objs, and returns the result. If
+// [TemplateOpts.CreateNamespace] is set and objs does not include a matching
+// Namespace, one is added first.
+func (pr *postRenderer) render(objs []kube.Object) []kube.Object {
+	if pr.opts.CreateNamespace && !hasNamespace(objs, pr.opts.Namespace) {
+		objs = append([]kube.Object{{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]any{"name": pr.opts.Namespace},
+		}}, objs...)
+	}
+
+	for _, obj := range objs {
+		pr.apply(obj)
+	}
+
+	return objs
+}
+
+// apply applies the post-renderers to obj in place.
+func (pr *postRenderer) apply(obj kube.Object) {
+	metadata, ok := obj["metadata"].(map[string]any)
+	if !ok {
+		metadata = map[string]any{}
+		obj["metadata"] = metadata
+	}
+
+	namespaced := pr.isNamespaced(obj)
+
+	if pr.opts.ForceNamespace && namespaced {
+		metadata["namespace"] = pr.opts.Namespace
+	}
+
+	setStringMap(metadata, "labels", pr.opts.CommonLabels)
+	setStringMap(metadata, "annotations", pr.opts.CommonAnnotations)
+
+	if pr.opts.ArgoCDAppName != "" {
+		namespace := ""
+		if namespaced {
+			namespace = obj.GetNamespace()
+			if namespace == "" {
+				namespace = pr.opts.Namespace
+			}
+		}
+
+		group := apiGroup(obj.GetAPIVersion())
+
+		setStringMap(metadata, "annotations", map[string]string{
+			ArgoCDTrackingIDAnnotation: fmt.Sprintf("%s:%s/%s:%s/%s",
+				pr.opts.ArgoCDAppName, group, obj.GetKind(), namespace, obj.GetName()),
+		})
+	}
+}
+
+// isNamespaced returns true if obj is not a known cluster-scoped resource.
+func (pr *postRenderer) isNamespaced(obj kube.Object) bool {
+	group := apiGroup(obj.GetAPIVersion())
+	gk := groupKind(group, obj.GetKind())
+
+	return !clusterScopedKinds[gk] && !pr.customClusterScoped[gk]
+}
+
+// hasNamespace returns true if objs includes the Namespace name.
+func hasNamespace(objs []kube.Object, name string) bool {
+	for _, obj := range objs {
+		if obj.GetAPIVersion() == "v1" && obj.GetKind() == "Namespace" && obj.GetName() == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setStringMap sets the entries of values in the map at metadata[field],
+// creating it if needed. Existing entries with the same keys are replaced.
+func setStringMap(metadata map[string]any, field string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+
+	m, ok := metadata[field].(map[string]any)
+	if !ok {
+		m = make(map[string]any, len(values))
+		metadata[field] = m
+	}
+
+	for k, v := range values {
+		m[k] = v
+	}
+}
+
+// apiGroup returns the group of an apiVersion. The group of core resources
+// is empty.
+func apiGroup(apiVersion string) string {
+	group, _, ok := strings.Cut(apiVersion, "/")
+	if !ok {
+		return ""
+	}
+
+	return group
+}
+
+// groupKind returns the key of the given group and kind in
+// clusterScopedKinds, e.g. `ClusterRole.rbac.authorization.k8s.io`.
+func groupKind(group, kind string) string {
+	if group == "" {
+		return kind
+	}
+
+	return kind + "." + group
+}
@@ -9,7 +9,11 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
+	"sync"
 
+	"github.com/gofrs/flock"
 	"golang.org/x/sync/semaphore"
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -17,27 +21,217 @@ import (
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 
 	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/paths"
 )
 
 // ErrChartDependency indicates an error occurred while loading chart dependencies.
 var ErrChartDependency = errors.New("chart dependency")
 
 // PulledChart represents a Helm chart.tar.gz, or the root directory of a Helm
-// chart. It is typically created via [Client.Pull].
+// chart. It is typically created via [Client.Pull]. Charts in the chart cache
+// stay locked until [PulledChart.Close] is called, so that they are not evicted
+// while they are in use, by this or any other process.
 type PulledChart struct {
 	repos  helmrepo.Getter
 	client ChartClient
-	chart  string
-	path   string
+	// entryLock is the lock of the chart's cache entry, see
+	// [newCacheEntryLock]. It is nil for charts which are not cached.
+	entryLock *flock.Flock
+	chart     string
+	repo      string
+	path      string
+	// root is the directory which `file://` dependencies of the chart must
+	// stay within. It is empty for charts from remote repositories, whose
+	// `file://` dependencies must be packaged with them.
+	root    string
+	version string
+	deps    []*PulledChart
+	// entryRefs counts the holders of entryLock, i.e. the [PulledChart]
+	// itself until it is closed, and any reads in progress.
+	entryRefs int
+	mu        sync.Mutex
+	entryMu   sync.Mutex
+	closed    bool
+}
+
+// holdEntry sets the lock of the chart's cache entry, which is held shared
+// until [PulledChart.Close] is called.
+func (c *PulledChart) holdEntry(lock *flock.Flock) {
+	c.entryLock = lock
+	c.entryRefs = 1
+}
+
+// acquireEntry locks the chart's cache entry, if it is not already locked, so
+// that the chart can be read. Each call must be followed by a call to
+// [PulledChart.releaseEntry].
+func (c *PulledChart) acquireEntry() error {
+	if c.entryLock == nil {
+		return nil
+	}
+
+	c.entryMu.Lock()
+	defer c.entryMu.Unlock()
+
+	if c.entryRefs == 0 {
+		err := c.entryLock.RLock()
+		if err != nil {
+			return fmt.Errorf("lock cache entry: %w", err)
+		}
+	}
+
+	c.entryRefs++
+
+	return nil
+}
+
+// releaseEntry unlocks the chart's cache entry once it has no more holders.
+func (c *PulledChart) releaseEntry() error {
+	if c.entryLock == nil {
+		return nil
+	}
+
+	c.entryMu.Lock()
+	defer c.entryMu.Unlock()
+
+	c.entryRefs--
+	if c.entryRefs > 0 {
+		return nil
+	}
+
+	err := c.entryLock.Unlock()
+	if err != nil {
+		return fmt.Errorf("unlock cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// Close releases the locks on the cache entries of the chart and of the
+// dependencies pulled by [PulledChart.Load], so that they can be evicted from
+// the chart cache. The chart can still be read afterwards, in which case it is
+// locked again for the duration of each read, but paths returned by
+// [PulledChart.Extract] for chart directories are no longer guaranteed to
+// exist. Calling Close more than once has no effect.
+func (c *PulledChart) Close() error {
+	c.mu.Lock()
+	closed := c.closed
+	deps := c.deps
+	c.closed = true
+	c.mu.Unlock()
+
+	if closed {
+		return nil
+	}
+
+	var errs error
+
+	for _, dep := range deps {
+		errs = errors.Join(errs, dep.Close())
+	}
+
+	return errors.Join(errs, c.releaseEntry())
+}
+
+// Name returns the name of the pulled chart.
+func (c *PulledChart) Name() string {
+	return c.chart
+}
+
+// RepoURL returns the repository URL that the chart was pulled from, as it was
+// passed to [Client.Pull].
+func (c *PulledChart) RepoURL() string {
+	return c.repo
+}
+
+// Version returns the exact version of the pulled chart, after any version
+// constraint was resolved. For local charts and remote charts pulled without a
+// target revision, it is empty until [PulledChart.Load] is called.
+func (c *PulledChart) Version() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.version
+}
+
+// Digest returns the sha256 digest of the pulled chart archive. It is empty
+// if the chart is a directory, e.g. a local chart.
+func (c *PulledChart) Digest() (string, error) {
+	err := c.acquireEntry()
+	if err != nil {
+		return "", err
+	}
+
+	defer c.releaseEntry() //nolint:errcheck // Unlocked when the file is closed.
+
+	if dirExists(c.path) {
+		return "", nil
+	}
+
+	return archiveDigest(c.path)
+}
+
+// hasUnpackagedDependencies returns true if the chart, or any of its packaged
+// subcharts, declares dependencies which are not packaged with it. These are
+// pulled by [PulledChart.Load], and may resolve to different charts over time.
+func (c *PulledChart) hasUnpackagedDependencies() (bool, error) {
+	err := c.acquireEntry()
+	if err != nil {
+		return false, err
+	}
+
+	defer c.releaseEntry() //nolint:errcheck // Unlocked when the file is closed.
+
+	loadedChart, err := loadChart(c.path)
+	if err != nil {
+		return false, fmt.Errorf("read chart from disk: %w", err)
+	}
+
+	return hasUnpackagedDependencies(loadedChart), nil
+}
+
+func hasUnpackagedDependencies(loadedChart *chart.Chart) bool {
+	if loadedChart.Metadata == nil {
+		return false
+	}
+
+	for _, dep := range loadedChart.Metadata.Dependencies {
+		i := slices.IndexFunc(loadedChart.Dependencies(), func(sub *chart.Chart) bool {
+			return sub.Name() == dep.Name
+		})
+		if i < 0 || hasUnpackagedDependencies(loadedChart.Dependencies()[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Dependencies returns the dependencies that were pulled by the most recent
+// call to [PulledChart.Load], including transitive dependencies. Dependencies
+// which are packaged with their parent chart are not included.
+func (c *PulledChart) Dependencies() []*PulledChart {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.deps)
 }
 
 // Extract will extract the chart (if it is a .tar.gz file), and return the path
 // to the extracted chart. An [io.Closer] is also returned, calling Close() will
 // clean up the extracted chart. If [PulledChart] references a directory,
-// the path to the directory and a [NewNopCloser] is returned.
+// the path to the directory and a [NewNopCloser] is returned, and the
+// directory may be evicted from the chart cache once [PulledChart.Close] is
+// called.
 func (c *PulledChart) Extract(maxSize *resource.Quantity) (string, io.Closer, error) {
 	raiseHelmArchiveLimits(maxSize.Value())
 
+	err := c.acquireEntry()
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer c.releaseEntry() //nolint:errcheck // Unlocked when the file is closed.
+
 	closer := NewNopCloser()
 
 	// If the chart is already extracted, return the path to the extracted chart.
@@ -57,13 +251,38 @@ func (c *PulledChart) Extract(maxSize *resource.Quantity) (string, io.Closer, er
 // references a .tar.gz, it will be loaded directly into memory without
 // extracting the files to disk. If [PulledChart] references a directory, the
 // contents of the chart will be loaded from the filesystem. No closer is
-// returned by this method, since no temporary files are created.
+// returned by this method, since no temporary files are created. Dependencies
+// pulled by previous calls are closed, see [PulledChart.Close].
 func (c *PulledChart) Load(ctx context.Context) (*chart.Chart, error) {
+	err := c.acquireEntry()
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.releaseEntry() //nolint:errcheck // Unlocked when the file is closed.
+
+	c.mu.Lock()
+	deps := c.deps
+	c.deps = nil
+	c.mu.Unlock()
+
+	for _, dep := range deps {
+		tryClose(dep)
+	}
+
 	loadedChart, err := loadChart(c.path)
 	if err != nil {
 		return nil, fmt.Errorf("read chart from disk: %w", err)
 	}
 
+	if loadedChart.Metadata != nil {
+		c.mu.Lock()
+		if c.version == "" {
+			c.version = loadedChart.Metadata.Version
+		}
+		c.mu.Unlock()
+	}
+
 	// Recursively load and set all chart dependencies.
 	err = c.loadChartDependencies(ctx, loadedChart)
 	if err != nil {
@@ -104,19 +323,23 @@ func (c *PulledChart) loadChartDependencies(ctx context.Context, target *chart.C
 	workerCount := int64(runtime.GOMAXPROCS(0))
 	sem := semaphore.NewWeighted(workerCount)
 
-	return c.setChartDependencies(ctx, target, sem)
+	return c.setChartDependencies(ctx, target, c, sem)
 }
 
+// setChartDependencies loads and sets the dependencies of the target chart,
+// which was loaded from source.
 func (c *PulledChart) setChartDependencies(
 	ctx context.Context,
 	target *chart.Chart,
+	source *PulledChart,
 	sem *semaphore.Weighted,
 ) error {
 	loadedDeps := []*chart.Chart{}
 
 	type loadResult struct {
-		chart *chart.Chart
-		err   error
+		chart  *chart.Chart
+		source *PulledChart
+		err    error
 	}
 
 	depCount := int64(len(target.Metadata.Dependencies))
@@ -139,14 +362,14 @@ func (c *PulledChart) setChartDependencies(
 			defer sem.Release(1)
 			defer innerSem.Release(1)
 
-			dep, err := c.getChartDependency(ctx, target, chartDep)
+			dep, depSource, err := c.getChartDependency(ctx, target, source, chartDep)
 			if err != nil {
 				resultCh <- loadResult{err: fmt.Errorf("get dependency %q: %w", target.Name(), err)}
 
 				return
 			}
 
-			resultCh <- loadResult{chart: dep}
+			resultCh <- loadResult{chart: dep, source: depSource}
 		}()
 	}
 
@@ -166,7 +389,7 @@ func (c *PulledChart) setChartDependencies(
 			continue
 		}
 
-		err := c.setChartDependencies(ctx, result.chart, sem)
+		err := c.setChartDependencies(ctx, result.chart, result.source, sem)
 		if err != nil {
 			return fmt.Errorf("set chart dependencies: %w", err)
 		}
@@ -183,33 +406,105 @@ func (c *PulledChart) setChartDependencies(
 	return nil
 }
 
+// getChartDependency returns the dependency dep of parentChart, which was
+// loaded from parent, and the [PulledChart] that the dependency was loaded
+// from.
 func (c *PulledChart) getChartDependency(
 	ctx context.Context,
 	parentChart *chart.Chart,
+	parent *PulledChart,
 	dep *chart.Dependency,
-) (*chart.Chart, error) {
+) (*chart.Chart, *PulledChart, error) {
 	// Check if the dependency is already loaded.
 	for _, includedDep := range parentChart.Dependencies() {
 		if includedDep.Name() == dep.Name {
-			return includedDep, nil
+			// Packaged dependencies have no source of their own, so any
+			// dependencies they are missing cannot be local.
+			return includedDep, &PulledChart{chart: dep.Name}, nil
 		}
 	}
 
 	if dep.Repository == "" {
-		return nil, fmt.Errorf("chart dependency has no repository: %#v", dep)
+		return nil, nil, fmt.Errorf("chart dependency has no repository: %#v", dep)
+	}
+
+	var (
+		pulledChart *PulledChart
+		err         error
+	)
+
+	if strings.HasPrefix(dep.Repository, "file://") {
+		pulledChart, err = c.getFileDependency(parent, dep)
+	} else {
+		pulledChart, err = c.client.Pull(ctx, dep.Name, dep.Repository, dep.Version, c.repos)
 	}
 
-	pulledChart, err := c.client.Pull(ctx, dep.Name, dep.Repository, dep.Version, c.repos)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrChartPull, err)
+		return nil, nil, fmt.Errorf("%w: %w", ErrChartPull, err)
 	}
 
 	depChart, err := loadChart(pulledChart.path)
 	if err != nil {
-		return nil, fmt.Errorf("load chart dependency: %w", err)
+		tryClose(pulledChart)
+
+		return nil, nil, fmt.Errorf("load chart dependency: %w", err)
+	}
+
+	// Dependencies stay locked until the chart is closed, since their own
+	// `file://` dependencies are loaded from them.
+	c.mu.Lock()
+	closed := c.closed
+	c.deps = append(c.deps, pulledChart)
+	c.mu.Unlock()
+
+	if closed {
+		tryClose(pulledChart)
+	}
+
+	return depChart, pulledChart, nil
+}
+
+// getFileDependency returns the `file://` dependency dep of parent. Like in
+// Helm, the path is relative to the parent chart's directory, and it may point
+// at the dependency's chart directory itself. Otherwise, it is treated as a
+// local repository, see [localChartPath]. The path must stay within the
+// parent's root, i.e. the repository root for local charts, or the checkout
+// for charts from Git repositories.
+func (c *PulledChart) getFileDependency(parent *PulledChart, dep *chart.Dependency) (*PulledChart, error) {
+	if parent.root == "" || !dirExists(parent.path) {
+		return nil, fmt.Errorf("dependency %q of chart %q must be packaged with it: %q",
+			dep.Name, parent.chart, dep.Repository)
+	}
+
+	depPath, err := paths.ResolveFileOrDirectoryPath(
+		parent.path, parent.root, strings.TrimPrefix(dep.Repository, "file://"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %q: %w", dep.Repository, err)
+	}
+
+	chartPath := depPath.String()
+
+	exists, err := fileExists(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("check chart path: %w", err)
+	}
+
+	if !exists {
+		chartPath, err = localChartPath(chartPath, dep.Name, dep.Version)
+		if err != nil {
+			return nil, fmt.Errorf("get local chart: %w", err)
+		}
 	}
 
-	return depChart, nil
+	return &PulledChart{
+		repos:  c.repos,
+		client: c.client,
+		chart:  dep.Name,
+		repo:   dep.Repository,
+		path:   chartPath,
+		root:   parent.root,
+	}, nil
 }
 
 // loadChart loads the chart at chartPath via Helm's loader. The loader reads
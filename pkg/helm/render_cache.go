@@ -0,0 +1,342 @@
+package helm
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.jacobcolvin.com/x/version"
+
+	"github.com/macropower/kclipper/pkg/kube"
+)
+
+// RenderCache stores rendered chart output on disk, so that [Chart.Template]
+// can skip loading and templating charts which were already rendered with the
+// same options. Create instances with [NewRenderCache].
+//
+// Entries are keyed by the chart's name, repository, version and archive
+// digest, the versions and archive digests of the dependencies that were
+// pulled for it, the [TemplateOpts] that affect rendering, and the kclipper
+// version. Charts which are not archives (e.g. local charts), or which have
+// dependencies that are not archives, are never cached.
+//
+// By default, entries are never evicted. See [WithRenderCacheLimits].
+type RenderCache struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+	// pruneMu prevents concurrent puts from pruning the cache at the same time.
+	pruneMu sync.Mutex
+}
+
+// RenderCacheOption is a functional option for [NewRenderCache].
+type RenderCacheOption func(*RenderCache)
+
+// WithRenderCacheLimits returns a [RenderCacheOption] that bounds the size of
+// the [RenderCache], in the same way as [WithCacheLimits] bounds the chart
+// cache. After each entry is stored, the least recently used entries are
+// evicted until the cache is no larger than maxSize bytes, and entries which
+// have not been used for longer than maxAge are evicted. A zero value disables
+// the respective limit.
+func WithRenderCacheLimits(maxSize int64, maxAge time.Duration) RenderCacheOption {
+	return func(rc *RenderCache) {
+		rc.maxSize = maxSize
+		rc.maxAge = maxAge
+	}
+}
+
+// NewRenderCache creates a new [RenderCache] which stores entries in dir.
+func NewRenderCache(dir string, opts ...RenderCacheOption) (*RenderCache, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("create render cache directory: %w", err)
+	}
+
+	rc := &RenderCache{dir: dir}
+	for _, opt := range opts {
+		opt(rc)
+	}
+
+	return rc, nil
+}
+
+// renderCacheKey is hashed to identify an entry in a [RenderCache]. Fields
+// which do not affect the rendered output (e.g. timeouts and sort order) are
+// excluded.
+type renderCacheKey struct {
+	Values               map[string]any          `json:"values"`
+	SetValues            *SetValues              `json:"setValues"`
+	Kclipper             string                  `json:"kclipper"`
+	Chart                string                  `json:"chart"`
+	RepoURL              string                  `json:"repoURL"`
+	Version              string                  `json:"version"`
+	Digest               string                  `json:"digest"`
+	ReleaseName          string                  `json:"releaseName"`
+	Namespace            string                  `json:"namespace"`
+	KubeVersion          string                  `json:"kubeVersion"`
+	ValueFiles           []*ValuesFile           `json:"valueFiles"`
+	Dependencies         []renderCacheDependency `json:"dependencies"`
+	APIVersions          []string                `json:"apiVersions"`
+	Lookups              []kube.Object           `json:"lookups"`
+	SkipCRDs             bool                    `json:"skipCRDs"`
+	SkipSchemaValidation bool                    `json:"skipSchemaValidation"`
+	SkipHooks            bool                    `json:"skipHooks"`
+}
+
+// renderCacheDependency identifies a dependency in a [renderCacheKey].
+type renderCacheDependency struct {
+	Chart   string `json:"chart"`
+	RepoURL string `json:"repoURL"`
+	Version string `json:"version"`
+	Digest  string `json:"digest"`
+}
+
+// key returns the [RenderCache] key for rendering pulledChart with t. It
+// returns an empty key if the chart cannot be cached. Dependencies which are
+// not packaged with the chart must have been pulled by [PulledChart.Load].
+func (rc *RenderCache) key(pulledChart *PulledChart, t *TemplateOpts) (string, error) {
+	dgst, err := pulledChart.Digest()
+	if err != nil {
+		return "", fmt.Errorf("get chart digest: %w", err)
+	}
+
+	if dgst == "" {
+		return "", nil
+	}
+
+	deps := pulledChart.Dependencies()
+	depKeys := make([]renderCacheDependency, 0, len(deps))
+
+	for _, dep := range deps {
+		depDigest, err := dep.Digest()
+		if err != nil {
+			return "", fmt.Errorf("get dependency digest: %w", err)
+		}
+
+		if depDigest == "" {
+			return "", nil
+		}
+
+		depKeys = append(depKeys, renderCacheDependency{
+			Chart:   dep.Name(),
+			RepoURL: dep.RepoURL(),
+			Version: dep.Version(),
+			Digest:  depDigest,
+		})
+	}
+
+	// Dependencies are pulled concurrently, so their order is not stable.
+	slices.SortFunc(depKeys, func(a, b renderCacheDependency) int {
+		return cmp.Or(
+			strings.Compare(a.Chart, b.Chart),
+			strings.Compare(a.RepoURL, b.RepoURL),
+			strings.Compare(a.Version, b.Version),
+			strings.Compare(a.Digest, b.Digest),
+		)
+	})
+
+	values := t.ValuesObject
+	if values == nil {
+		values = map[string]any{}
+	}
+
+	releaseName := t.ReleaseName
+	if releaseName == "" {
+		releaseName = t.ChartName
+	}
+
+	// The order of API versions does not affect the rendered output.
+	apiVersions := slices.Clone(t.APIVersions)
+	slices.Sort(apiVersions)
+
+	// Map keys are sorted when marshaled, so equal options produce equal keys.
+	keyData, err := json.Marshal(renderCacheKey{
+		Kclipper:             version.Version,
+		Chart:                pulledChart.Name(),
+		RepoURL:              pulledChart.RepoURL(),
+		Version:              pulledChart.Version(),
+		Digest:               dgst,
+		Values:               values,
+		SetValues:            t.SetValues,
+		ValueFiles:           t.ValueFiles,
+		Dependencies:         depKeys,
+		ReleaseName:          releaseName,
+		Namespace:            t.Namespace,
+		KubeVersion:          t.KubeVersion,
+		APIVersions:          apiVersions,
+		Lookups:              t.Lookups,
+		SkipCRDs:             t.SkipCRDs,
+		SkipSchemaValidation: t.SkipSchemaValidation,
+		SkipHooks:            t.SkipHooks,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal render cache key: %w", err)
+	}
+
+	sum := sha256.Sum256(keyData)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (rc *RenderCache) path(key string) string {
+	return filepath.Join(rc.dir, key+".yaml")
+}
+
+// get returns the rendered output stored for key, if there is one.
+func (rc *RenderCache) get(ctx context.Context, key string) ([]byte, bool, error) {
+	entryPath := rc.path(key)
+
+	out, err := os.ReadFile(entryPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("read render cache: %w", err)
+	}
+
+	// Record the access, so that the render cache can be pruned by last use.
+	now := time.Now()
+
+	err = os.Chtimes(entryPath, now, now)
+	if err != nil {
+		slog.DebugContext(ctx, "update cached render access time",
+			slog.String("path", entryPath),
+			slog.Any("err", err),
+		)
+	}
+
+	return out, true, nil
+}
+
+// put stores the rendered output for key, and then prunes the cache according
+// to its limits. The entry is written to a temporary file first, so that
+// concurrent readers never see a partial entry.
+func (rc *RenderCache) put(ctx context.Context, key string, out []byte) error {
+	f, err := os.CreateTemp(rc.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create render cache entry: %w", err)
+	}
+
+	_, err = f.Write(out)
+	if err == nil {
+		err = f.Close()
+	} else {
+		_ = f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), rc.path(key))
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+
+		return fmt.Errorf("write render cache entry: %w", err)
+	}
+
+	rc.pruneAfterPut(ctx, key)
+
+	return nil
+}
+
+// pruneAfterPut prunes the cache after the entry for key was stored. Pruning
+// is skipped if it is already running in another goroutine, and errors are
+// logged rather than failing the render.
+func (rc *RenderCache) pruneAfterPut(ctx context.Context, key string) {
+	if !rc.pruneMu.TryLock() {
+		return
+	}
+	defer rc.pruneMu.Unlock()
+
+	err := rc.prune(ctx, rc.path(key))
+	if err != nil {
+		slog.WarnContext(ctx, "prune render cache", slog.Any("err", err))
+	}
+}
+
+// renderCacheEntry is an entry listed by [RenderCache.prune].
+type renderCacheEntry struct {
+	lastAccess time.Time
+	path       string
+	size       int64
+}
+
+// prune evicts entries according to the cache limits, least recently used
+// first. The entry at keep is never evicted. Readers which have already
+// opened an evicted entry can still read it, and readers which have not
+// treat it as a cache miss.
+func (rc *RenderCache) prune(ctx context.Context, keep string) error {
+	if rc.maxSize <= 0 && rc.maxAge <= 0 {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(rc.dir, "*.yaml"))
+	if err != nil {
+		return fmt.Errorf("list render cache entries: %w", err)
+	}
+
+	entries := make([]renderCacheEntry, 0, len(paths))
+
+	var size int64
+
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("stat render cache entry: %w", err)
+		}
+
+		entries = append(entries, renderCacheEntry{
+			path:       p,
+			size:       fi.Size(),
+			lastAccess: fi.ModTime(),
+		})
+		size += fi.Size()
+	}
+
+	slices.SortStableFunc(entries, func(a, b renderCacheEntry) int {
+		return a.lastAccess.Compare(b.lastAccess)
+	})
+
+	now := time.Now()
+
+	for _, e := range entries {
+		expired := rc.maxAge > 0 && now.Sub(e.lastAccess) > rc.maxAge
+		oversize := rc.maxSize > 0 && size > rc.maxSize
+
+		if !expired && !oversize {
+			// Entries are sorted by last access, so the rest are newer.
+			break
+		}
+
+		if e.path == keep {
+			continue
+		}
+
+		err := os.Remove(e.path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove render cache entry %q: %w", e.path, err)
+		}
+
+		slog.DebugContext(ctx, "evicted cached render",
+			slog.String("path", e.path),
+			slog.Int64("size", e.size),
+			slog.Time("last_access", e.lastAccess),
+		)
+
+		size -= e.size
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package helm_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/helmrepo"
+)
+
+func TestChartTemplateRenderCache(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3", "1.2.5"})
+	client := newTestClient(t)
+
+	cacheDir := t.TempDir()
+	renderCache, err := helm.NewRenderCache(cacheDir)
+	require.NoError(t, err)
+
+	template := func(t *testing.T, opts *helm.TemplateOpts) string {
+		t.Helper()
+
+		opts.ChartName = "test-chart"
+		opts.RepoURL = srv.URL
+
+		c := helm.NewChart(client, helmrepo.DefaultManager, opts)
+		c.RenderCache = renderCache
+
+		objs, err := c.Template(t.Context())
+		require.NoError(t, err)
+		require.Len(t, objs, 1)
+
+		return objs[0].GetName()
+	}
+
+	assert.Equal(t, "test-chart", template(t, &helm.TemplateOpts{
+		TargetRevision: "1.2.3",
+		APIVersions:    []string{"a/v1", "b/v1"},
+		ValuesObject:   map[string]any{"a": 1, "b": 2},
+	}))
+
+	entries, err := filepath.Glob(filepath.Join(cacheDir, "*.yaml"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// Replace the cached output, so that cache hits can be observed.
+	cached := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cached\n"
+	require.NoError(t, os.WriteFile(entries[0], []byte(cached), 0o600))
+
+	tcs := map[string]struct {
+		opts *helm.TemplateOpts
+		want string
+	}{
+		"equal options": {
+			opts: &helm.TemplateOpts{
+				TargetRevision: "1.2.3",
+				APIVersions:    []string{"b/v1", "a/v1"},
+				ValuesObject:   map[string]any{"b": 2, "a": 1},
+				Sort:           helm.SortOrderKind,
+			},
+			want: "cached",
+		},
+		"constraint resolving to the same version": {
+			opts: &helm.TemplateOpts{
+				TargetRevision: "~1.2.0 <1.2.4",
+				APIVersions:    []string{"a/v1", "b/v1"},
+				ValuesObject:   map[string]any{"a": 1, "b": 2},
+			},
+			want: "cached",
+		},
+		"different values": {
+			opts: &helm.TemplateOpts{
+				TargetRevision: "1.2.3",
+				APIVersions:    []string{"a/v1", "b/v1"},
+				ValuesObject:   map[string]any{"a": 1, "b": 3},
+			},
+			want: "test-chart",
+		},
+		"different version": {
+			opts: &helm.TemplateOpts{
+				TargetRevision: "1.2.5",
+				APIVersions:    []string{"a/v1", "b/v1"},
+				ValuesObject:   map[string]any{"a": 1, "b": 2},
+			},
+			want: "test-chart",
+		},
+		"different release name": {
+			opts: &helm.TemplateOpts{
+				TargetRevision: "1.2.3",
+				ReleaseName:    "other",
+				APIVersions:    []string{"a/v1", "b/v1"},
+				ValuesObject:   map[string]any{"a": 1, "b": 2},
+			},
+			want: "other",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, template(t, tc.opts))
+		})
+	}
+}
+
+func TestChartTemplateRenderCacheDependencies(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	vendorDir := filepath.Join(root, "vendor")
+	require.NoError(t, os.MkdirAll(vendorDir, 0o700))
+
+	writeArchive := func(name, version string, archive []byte) {
+		t.Helper()
+
+		archivePath := filepath.Join(vendorDir, name+"-"+version+".tgz")
+		require.NoError(t, os.WriteFile(archivePath, archive, 0o600))
+	}
+
+	// The parent chart's dependency has a version range, so the subchart
+	// that it renders with changes when a newer version is added.
+	writeArchive("parent-chart", "1.0.0", chartArchiveWithDependencies(t, "parent-chart", "1.0.0",
+		"  - name: test-chart\n    version: ~1.2.0\n    repository: ./vendor\n"))
+	writeArchive("test-chart", "1.2.3", chartArchive(t, "test-chart", "1.2.3"))
+
+	repos := helmrepo.NewManager(helmrepo.WithAllowedPaths(root, root))
+	client := newTestClient(t)
+
+	cacheDir := t.TempDir()
+	renderCache, err := helm.NewRenderCache(cacheDir)
+	require.NoError(t, err)
+
+	// template returns the versions in the rendered ConfigMaps.
+	template := func(t *testing.T) []string {
+		t.Helper()
+
+		c := helm.NewChart(client, repos, &helm.TemplateOpts{
+			ChartName:      "parent-chart",
+			RepoURL:        "./vendor",
+			TargetRevision: "1.0.0",
+		})
+		c.RenderCache = renderCache
+
+		objs, err := c.Template(t.Context())
+		require.NoError(t, err)
+
+		versions := make([]string, 0, len(objs))
+		for _, obj := range objs {
+			data, ok := obj["data"].(map[string]any)
+			require.True(t, ok)
+
+			version, ok := data["version"].(string)
+			require.True(t, ok)
+
+			versions = append(versions, version)
+		}
+
+		slices.Sort(versions)
+
+		return versions
+	}
+
+	assert.Equal(t, []string{"1.0.0", "1.2.3"}, template(t))
+
+	entries, err := filepath.Glob(filepath.Join(cacheDir, "*.yaml"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// Replace the cached output, so that cache hits can be observed.
+	cached := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cached\ndata:\n  version: cached\n"
+	require.NoError(t, os.WriteFile(entries[0], []byte(cached), 0o600))
+
+	assert.Equal(t, []string{"cached"}, template(t))
+
+	writeArchive("test-chart", "1.2.5", chartArchive(t, "test-chart", "1.2.5"))
+
+	assert.Equal(t, []string{"1.0.0", "1.2.5"}, template(t))
+}
+
+func TestChartTemplateRenderCacheLimits(t *testing.T) {
+	t.Parallel()
+
+	srv := newChartServer(t, "test-chart", []string{"1.2.3"})
+	client := newTestClient(t)
+
+	tcs := map[string]struct {
+		opts []helm.RenderCacheOption
+		want []string
+	}{
+		"no limits": {
+			want: []string{"expired.yaml", "recent.yaml"},
+		},
+		"max age": {
+			opts: []helm.RenderCacheOption{helm.WithRenderCacheLimits(0, time.Hour)},
+			want: []string{"recent.yaml"},
+		},
+		"max size": {
+			opts: []helm.RenderCacheOption{helm.WithRenderCacheLimits(1, 0)},
+			want: []string{},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cacheDir := t.TempDir()
+			renderCache, err := helm.NewRenderCache(cacheDir, tc.opts...)
+			require.NoError(t, err)
+
+			writeEntry := func(name string, lastAccess time.Time) {
+				t.Helper()
+
+				entryPath := filepath.Join(cacheDir, name)
+				require.NoError(t, os.WriteFile(entryPath, []byte("kind: ConfigMap\n"), 0o600))
+				require.NoError(t, os.Chtimes(entryPath, lastAccess, lastAccess))
+			}
+
+			writeEntry("expired.yaml", time.Now().Add(-2*time.Hour))
+			writeEntry("recent.yaml", time.Now().Add(-time.Minute))
+
+			c := helm.NewChart(client, helmrepo.DefaultManager, &helm.TemplateOpts{
+				ChartName:      "test-chart",
+				RepoURL:        srv.URL,
+				TargetRevision: "1.2.3",
+			})
+			c.RenderCache = renderCache
+
+			_, err = c.Template(t.Context())
+			require.NoError(t, err)
+
+			entries, err := filepath.Glob(filepath.Join(cacheDir, "*.yaml"))
+			require.NoError(t, err)
+
+			// The entry which was just stored is never evicted.
+			require.Len(t, entries, len(tc.want)+1)
+
+			names := []string{}
+			for _, e := range entries {
+				if name := filepath.Base(e); name == "expired.yaml" || name == "recent.yaml" {
+					names = append(names, name)
+				}
+			}
+
+			assert.Equal(t, tc.want, names)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"helm.sh/helm/v4/pkg/strvals"
+
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+// ErrSetValues indicates that Helm-style value overrides could not be applied.
+var ErrSetValues = errors.New("set values")
+
+// SetValues are Helm-style value overrides, in the format accepted by the
+// `--set`, `--set-string` and `--set-file` flags of `helm template`. Each
+// entry may hold several comma-separated overrides, and keys may address
+// nested values and list items, e.g. `ingress.hosts[0].host=example.com`.
+type SetValues struct {
+	// Files holds the contents of the files referenced by
+	// [SetValues.SetFile], keyed by path as given. It is populated by
+	// [SetValues.ReadFiles].
+	Files map[string]string `json:"files,omitempty"`
+	// Set holds `key=value` overrides. Values are typed, e.g. `true` is a
+	// boolean and `1` is an integer.
+	Set []string `json:"set,omitempty"`
+	// SetString holds `key=value` overrides whose values are always strings.
+	SetString []string `json:"setString,omitempty"`
+	// SetFile holds `key=path` overrides, whose values are the contents of
+	// the files at the given paths.
+	SetFile []string `json:"setFile,omitempty"`
+}
+
+// ReadFiles reads the files referenced by [SetValues.SetFile] into
+// [SetValues.Files]. Paths are resolved with
+// [paths.ResolveFileOrDirectoryPath], so they must be within repoRoot.
+func (s *SetValues) ReadFiles(currentPath, repoRoot string) error {
+	files := make(map[string]string, len(s.SetFile))
+
+	readFile := func(rs []rune) (any, error) {
+		p, err := paths.ResolveFileOrDirectoryPath(currentPath, repoRoot, string(rs))
+		if err != nil {
+			return nil, fmt.Errorf("resolve file path: %w", err)
+		}
+
+		data, err := os.ReadFile(p.String())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrFileRead, err)
+		}
+
+		files[string(rs)] = string(data)
+
+		return "", nil
+	}
+
+	// Values may be secret, so errors only identify overrides by index.
+	for i, v := range s.SetFile {
+		err := strvals.ParseIntoFile(v, map[string]any{}, readFile)
+		if err != nil {
+			return fmt.Errorf("%w: parse setFile[%d]: %w", ErrSetValues, i, err)
+		}
+	}
+
+	s.Files = files
+
+	return nil
+}
+
+// MergeInto applies the overrides to values in place, in the same order as
+// Helm: [SetValues.Set], then [SetValues.SetString], then
+// [SetValues.SetFile]. Files must have been read with [SetValues.ReadFiles].
+func (s *SetValues) MergeInto(values map[string]any) error {
+	// Values may be secret, so errors only identify overrides by index.
+	for i, v := range s.Set {
+		err := strvals.ParseInto(v, values)
+		if err != nil {
+			return fmt.Errorf("%w: parse set[%d]: %w", ErrSetValues, i, err)
+		}
+	}
+
+	for i, v := range s.SetString {
+		err := strvals.ParseIntoString(v, values)
+		if err != nil {
+			return fmt.Errorf("%w: parse setString[%d]: %w", ErrSetValues, i, err)
+		}
+	}
+
+	readFile := func(rs []rune) (any, error) {
+		data, ok := s.Files[string(rs)]
+		if !ok {
+			return nil, fmt.Errorf("%w: file was not read", ErrFileRead)
+		}
+
+		return data, nil
+	}
+
+	for i, v := range s.SetFile {
+		err := strvals.ParseIntoFile(v, values, readFile)
+		if err != nil {
+			return fmt.Errorf("%w: parse setFile[%d]: %w", ErrSetValues, i, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package helm_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+func TestSetValuesMergeInto(t *testing.T) {
+	t.Parallel()
+
+	repoRoot := t.TempDir()
+	pkgPath := filepath.Join(repoRoot, "pkg")
+	require.NoError(t, os.MkdirAll(pkgPath, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, "tls.crt"), []byte("CERT\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(repoRoot, "outside.txt"), []byte("x"), 0o600))
+
+	tcs := map[string]struct {
+		values map[string]any
+		set    helm.SetValues
+		want   map[string]any
+		err    error
+	}{
+		"set": {
+			values: map[string]any{"image": map[string]any{"tag": "v1", "pullPolicy": "Always"}},
+			set: helm.SetValues{
+				Set: []string{"image.tag=v2,replicas=3", "ingress.hosts[0].host=example.com"},
+			},
+			want: map[string]any{
+				"image":    map[string]any{"tag": "v2", "pullPolicy": "Always"},
+				"replicas": int64(3),
+				"ingress":  map[string]any{"hosts": []any{map[string]any{"host": "example.com"}}},
+			},
+		},
+		"set string": {
+			set: helm.SetValues{
+				Set:       []string{"a=true", "b=1"},
+				SetString: []string{"b=1"},
+			},
+			want: map[string]any{"a": true, "b": "1"},
+		},
+		"set file": {
+			set: helm.SetValues{
+				Set:     []string{"tls.crt=placeholder"},
+				SetFile: []string{"tls.crt=tls.crt"},
+			},
+			want: map[string]any{"tls": map[string]any{"crt": "CERT\n"}},
+		},
+		"set file from repo root": {
+			set:  helm.SetValues{SetFile: []string{"outside=/outside.txt"}},
+			want: map[string]any{"outside": "x"},
+		},
+		"set file outside repo": {
+			set: helm.SetValues{SetFile: []string{"outside=../../outside.txt"}},
+			err: paths.ErrResolvedOutsideRepo,
+		},
+		"invalid set": {
+			set: helm.SetValues{Set: []string{"a"}},
+			err: helm.ErrSetValues,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			values := tc.values
+			if values == nil {
+				values = map[string]any{}
+			}
+
+			err := tc.set.ReadFiles(pkgPath, repoRoot)
+			if err == nil {
+				err = tc.set.MergeInto(values)
+			}
+
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, values)
+		})
+	}
+}
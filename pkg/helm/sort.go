@@ -0,0 +1,98 @@
+package helm
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	releaseutil "helm.sh/helm/v4/pkg/release/v1/util"
+
+	"github.com/macropower/kclipper/pkg/kube"
+)
+
+// SortOrder defines how rendered Kubernetes resources are ordered.
+type SortOrder string
+
+const (
+	// SortOrderDefault keeps resources in the order they were rendered.
+	SortOrderDefault SortOrder = ""
+	// SortOrderNone keeps resources in the order they were rendered.
+	SortOrderNone SortOrder = "NONE"
+	// SortOrderInstall orders resources by kind, in the order that Helm
+	// installs them (Namespaces, CRDs, RBAC, ConfigMaps, ..., workloads).
+	// Resources of the same kind are ordered by namespace, then by name.
+	SortOrderInstall SortOrder = "INSTALL"
+	// SortOrderKind orders resources alphabetically by kind, then by
+	// namespace, then by name.
+	SortOrderKind SortOrder = "KIND"
+)
+
+var (
+	// SortOrderEnum lists all valid sort orders.
+	SortOrderEnum = []any{
+		SortOrderNone,
+		SortOrderInstall,
+		SortOrderKind,
+	}
+
+	sortOrders = map[string]SortOrder{
+		string(SortOrderDefault): SortOrderDefault,
+		string(SortOrderNone):    SortOrderNone,
+		string(SortOrderInstall): SortOrderInstall,
+		string(SortOrderKind):    SortOrderKind,
+	}
+)
+
+// GetSortOrder returns the [SortOrder] matching the given string. Matching is
+// case-insensitive. An error is returned if the string is not recognized.
+func GetSortOrder(s string) (SortOrder, error) {
+	if so, ok := sortOrders[strings.TrimSpace(strings.ToUpper(s))]; ok {
+		return so, nil
+	}
+
+	return SortOrderDefault, fmt.Errorf("unknown sort order %q, expected one of %v", s, SortOrderEnum)
+}
+
+// SortObjects sorts objs in place according to the given [SortOrder]. The
+// sort is stable, so objects which compare equal keep their rendered order.
+func SortObjects(objs []kube.Object, order SortOrder) {
+	switch order {
+	case SortOrderInstall:
+		slices.SortStableFunc(objs, func(a, b kube.Object) int {
+			return cmp.Or(
+				compareInstallOrder(a.GetKind(), b.GetKind()),
+				cmp.Compare(a.GetNamespace(), b.GetNamespace()),
+				cmp.Compare(a.GetName(), b.GetName()),
+			)
+		})
+	case SortOrderKind:
+		slices.SortStableFunc(objs, func(a, b kube.Object) int {
+			return cmp.Or(
+				cmp.Compare(a.GetKind(), b.GetKind()),
+				cmp.Compare(a.GetNamespace(), b.GetNamespace()),
+				cmp.Compare(a.GetName(), b.GetName()),
+			)
+		})
+	case SortOrderDefault, SortOrderNone:
+	}
+}
+
+// compareInstallOrder compares two kinds by their position in Helm's
+// [releaseutil.InstallOrder]. As in Helm, unknown kinds are installed last,
+// in alphabetical order.
+func compareInstallOrder(kindA, kindB string) int {
+	idxA := slices.Index(releaseutil.InstallOrder, kindA)
+	idxB := slices.Index(releaseutil.InstallOrder, kindB)
+
+	switch {
+	case idxA == -1 && idxB == -1:
+		return cmp.Compare(kindA, kindB)
+	case idxA == -1:
+		return 1
+	case idxB == -1:
+		return -1
+	}
+
+	return cmp.Compare(idxA, idxB)
+}
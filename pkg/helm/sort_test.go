@@ -0,0 +1,120 @@
+package helm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/kube"
+)
+
+func TestSortObjects(t *testing.T) {
+	t.Parallel()
+
+	newObj := func(kind, namespace, name string) kube.Object {
+		metadata := map[string]any{"name": name}
+		if namespace != "" {
+			metadata["namespace"] = namespace
+		}
+
+		return kube.Object{
+			"apiVersion": "v1",
+			"kind":       kind,
+			"metadata":   metadata,
+		}
+	}
+
+	rendered := func() []kube.Object {
+		return []kube.Object{
+			newObj("Deployment", "b", "app"),
+			newObj("Widget", "a", "w"),
+			newObj("ConfigMap", "b", "z"),
+			newObj("Deployment", "a", "app"),
+			newObj("Gadget", "a", "g"),
+			newObj("ConfigMap", "b", "a"),
+			newObj("Namespace", "", "b"),
+			newObj("CustomResourceDefinition", "", "widgets.example.com"),
+		}
+	}
+
+	tcs := map[string]struct {
+		order helm.SortOrder
+		want  []string
+	}{
+		"default": {
+			order: helm.SortOrderDefault,
+			want: []string{
+				"Deployment/b/app",
+				"Widget/a/w",
+				"ConfigMap/b/z",
+				"Deployment/a/app",
+				"Gadget/a/g",
+				"ConfigMap/b/a",
+				"Namespace//b",
+				"CustomResourceDefinition//widgets.example.com",
+			},
+		},
+		"install": {
+			order: helm.SortOrderInstall,
+			want: []string{
+				"Namespace//b",
+				"ConfigMap/b/a",
+				"ConfigMap/b/z",
+				"CustomResourceDefinition//widgets.example.com",
+				"Deployment/a/app",
+				"Deployment/b/app",
+				"Gadget/a/g",
+				"Widget/a/w",
+			},
+		},
+		"kind": {
+			order: helm.SortOrderKind,
+			want: []string{
+				"ConfigMap/b/a",
+				"ConfigMap/b/z",
+				"CustomResourceDefinition//widgets.example.com",
+				"Deployment/a/app",
+				"Deployment/b/app",
+				"Gadget/a/g",
+				"Namespace//b",
+				"Widget/a/w",
+			},
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			objs := rendered()
+			helm.SortObjects(objs, tc.order)
+
+			got := make([]string, 0, len(objs))
+			for _, obj := range objs {
+				got = append(got, obj.GetKind()+"/"+obj.GetNamespace()+"/"+obj.GetName())
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestGetSortOrder(t *testing.T) {
+	t.Parallel()
+
+	got, err := helm.GetSortOrder("install")
+	require.NoError(t, err)
+	assert.Equal(t, helm.SortOrderInstall, got)
+
+	got, err = helm.GetSortOrder(" Kind ")
+	require.NoError(t, err)
+	assert.Equal(t, helm.SortOrderKind, got)
+
+	got, err = helm.GetSortOrder("")
+	require.NoError(t, err)
+	assert.Equal(t, helm.SortOrderDefault, got)
+
+	_, err = helm.GetSortOrder("random")
+	require.Error(t, err)
+}
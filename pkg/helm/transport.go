@@ -84,6 +84,64 @@ func (c *Client) getters(certFile, keyFile, caFile string, insecureSkipVerify bo
 	}, nil
 }
 
+// tlsSettings are the TLS settings of a repository, see [newTLSConfig].
+type tlsSettings struct {
+	certFile           string
+	keyFile            string
+	caFile             string
+	insecureSkipVerify bool
+}
+
+// httpClient returns an [*http.Client] for requests which are made without
+// Helm's getters. Like [Client.getters], it routes requests through the
+// [Client]'s proxy and carries the given TLS configuration. Clients are
+// created once for each TLS configuration, so that their connections are
+// reused.
+func (c *Client) httpClient(certFile, keyFile, caFile string, insecureSkipVerify bool) (*http.Client, error) {
+	key := tlsSettings{
+		certFile:           certFile,
+		keyFile:            keyFile,
+		caFile:             caFile,
+		insecureSkipVerify: insecureSkipVerify,
+	}
+
+	c.httpClientsMu.Lock()
+	defer c.httpClientsMu.Unlock()
+
+	if client, ok := c.httpClients[key]; ok {
+		return client, nil
+	}
+
+	tr := c.transport
+	if tr == nil {
+		tr, _ = http.DefaultTransport.(*http.Transport)
+	}
+
+	if tr == nil {
+		tr = &http.Transport{Proxy: http.ProxyFromEnvironment}
+	} else {
+		tr = tr.Clone()
+	}
+
+	tlsConf, err := newTLSConfig(certFile, keyFile, caFile, insecureSkipVerify)
+	if err != nil {
+		return nil, fmt.Errorf("configure tls: %w", err)
+	}
+
+	if tlsConf != nil {
+		tr.TLSClientConfig = tlsConf
+	}
+
+	if c.httpClients == nil {
+		c.httpClients = map[tlsSettings]*http.Client{}
+	}
+
+	client := &http.Client{Transport: tr}
+	c.httpClients[key] = client
+
+	return client, nil
+}
+
 // newTLSConfig creates a [*tls.Config] from repository TLS settings. It
 // returns nil when no TLS settings are provided.
 func newTLSConfig(certFile, keyFile, caFile string, insecureSkipVerify bool) (*tls.Config, error) {
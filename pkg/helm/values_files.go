@@ -0,0 +1,255 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+
+	"github.com/macropower/kclipper/pkg/kube"
+	"github.com/macropower/kclipper/pkg/offline"
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+const (
+	// ChartValuesFilePrefix marks a values file path as relative to the root
+	// directory of the chart, e.g. `chart://values-production.yaml`.
+	ChartValuesFilePrefix = "chart://"
+
+	// DefaultMaxValuesFileSize is the default maximum size of a values file
+	// fetched from a URL, see [WithValuesFileURLs].
+	DefaultMaxValuesFileSize int64 = 10 * 1024 * 1024 // 10Mi.
+)
+
+// ErrValuesFile indicates that a values file could not be read or parsed.
+var ErrValuesFile = errors.New("values file")
+
+// maxValuesFileRedirects is the maximum number of redirects that are followed
+// when a values file is fetched, like the default of [http.Client].
+const maxValuesFileRedirects = 10
+
+// valuesFileURLSchemes are the URL schemes that values files may be fetched
+// with.
+var valuesFileURLSchemes = []string{"http", "https"}
+
+// WithValuesFileURLs returns a [ClientOption] that allows values files to be
+// fetched from URLs, see [Client.ReadValuesFile]. Only URLs with one of
+// allowedHosts are fetched, including the targets of any redirects. A host
+// starting with `*.` matches any of its subdomains, and `*` matches any host.
+// Fetched files may be no larger than maxSize bytes, or
+// [DefaultMaxValuesFileSize] if maxSize is zero. Without this option, values
+// files cannot be fetched from URLs.
+func WithValuesFileURLs(allowedHosts []string, maxSize int64) ClientOption {
+	return func(c *Client) {
+		c.ValuesFileHosts = allowedHosts
+		c.MaxValuesFileSize = maxSize
+	}
+}
+
+// ValuesFile is a Helm values file, see [TemplateOpts.ValueFiles]. Create
+// instances with [Client.ReadValuesFile].
+type ValuesFile struct {
+	// Name identifies the file in errors, e.g. its path or URL.
+	Name string `json:"name"`
+	// ChartPath is the slash-separated path of the file within the chart. If
+	// set, the file is read from the chart when it is loaded, and Data is
+	// ignored.
+	ChartPath string `json:"chartPath,omitempty"`
+	// Data holds the contents of the file.
+	Data []byte `json:"data,omitempty"`
+}
+
+// ReadValuesFile reads the values file at file. Files with the
+// [ChartValuesFilePrefix] are not read until the chart is loaded. Other files
+// are resolved with [paths.ResolveFilePathOrURL], so they are relative to
+// currentPath (or to repoRoot, if they start with "/"), and must be within
+// repoRoot. Files may also be HTTP(S) URLs, which are fetched through the
+// [Client]'s proxy, subject to the restrictions set by [WithValuesFileURLs].
+func (c *Client) ReadValuesFile(ctx context.Context, file, currentPath, repoRoot string) (*ValuesFile, error) {
+	if chartPath, ok := strings.CutPrefix(file, ChartValuesFilePrefix); ok {
+		chartPath = path.Clean(chartPath)
+		if !filepath.IsLocal(filepath.FromSlash(chartPath)) {
+			return nil, fmt.Errorf("%w: %q: path must be within the chart", ErrValuesFile, file)
+		}
+
+		return &ValuesFile{Name: file, ChartPath: chartPath}, nil
+	}
+
+	resolved, err := paths.ResolveFilePathOrURL(currentPath, repoRoot, file, valuesFileURLSchemes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: resolve %q: %w", ErrValuesFile, file, err)
+	}
+
+	if u, ok := resolved.URL(); ok {
+		if !c.valuesFileHostAllowed(u.Hostname()) {
+			return nil, fmt.Errorf("%w: %q: host %q is not allowed", ErrValuesFile, u.Redacted(), u.Hostname())
+		}
+
+		data, err := c.fetchValuesFile(ctx, u.String())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrValuesFile, u.Redacted(), err)
+		}
+
+		return &ValuesFile{Name: u.Redacted(), Data: data}, nil
+	}
+
+	data, err := os.ReadFile(resolved.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValuesFile, err)
+	}
+
+	return &ValuesFile{Name: file, Data: data}, nil
+}
+
+// fetchValuesFile downloads the values file at fileURL.
+func (c *Client) fetchValuesFile(ctx context.Context, fileURL string) ([]byte, error) {
+	if c.offline() {
+		return nil, offline.ErrOffline
+	}
+
+	client, err := c.httpClient("", "", "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The client is shared, so set the redirect policy on a copy. Redirects
+	// are checked against the allowed hosts, so that an open redirect on an
+	// allowed host cannot be used to reach other hosts.
+	redirectClient := *client
+	redirectClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxValuesFileRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxValuesFileRedirects)
+		}
+
+		if !c.valuesFileHostAllowed(req.URL.Hostname()) {
+			return fmt.Errorf("redirect to host %q is not allowed", req.URL.Hostname())
+		}
+
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := redirectClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck // Best-effort close.
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download: %s", resp.Status)
+	}
+
+	maxSize := c.MaxValuesFileSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxValuesFileSize
+	}
+
+	// Read one byte past the limit, so that larger files can be detected.
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("file is larger than the maximum size of %d bytes", maxSize)
+	}
+
+	return data, nil
+}
+
+// valuesFileHostAllowed returns true if values files may be fetched from host,
+// see [WithValuesFileURLs].
+func (c *Client) valuesFileHostAllowed(host string) bool {
+	host = strings.ToLower(host)
+
+	for _, allowed := range c.ValuesFileHosts {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" {
+			return true
+		}
+
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+
+			continue
+		}
+
+		if host == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// values returns the values to render loadedChart with. Like the `-f` flag of
+// `helm template`, each of [TemplateOpts.ValueFiles] is merged over the
+// previous ones in order, followed by [TemplateOpts.ValuesObject]. Then, like
+// the `--set` flags, [TemplateOpts.SetValues] are applied to the result, so
+// that they can address items of lists from values files. Null values are
+// kept, so that they remove chart defaults when the values are coalesced.
+func (t *TemplateOpts) values(loadedChart *chart.Chart) (map[string]any, error) {
+	vals := map[string]any{}
+
+	for _, f := range t.ValueFiles {
+		data := f.Data
+		if f.ChartPath != "" {
+			var err error
+
+			data, err = chartFileData(loadedChart, f.ChartPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		fileVals, err := loader.LoadValues(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: parse %q: %w", ErrValuesFile, f.Name, err)
+		}
+
+		vals = loader.MergeMaps(vals, fileVals)
+	}
+
+	vals = loader.MergeMaps(vals, t.ValuesObject)
+
+	if t.SetValues != nil {
+		// Overrides are applied in place, and must not modify the values
+		// that were merged.
+		vals = kube.Object(vals).DeepCopy()
+
+		err := t.SetValues.MergeInto(vals)
+		if err != nil {
+			return nil, fmt.Errorf("apply value overrides: %w", err)
+		}
+	}
+
+	return vals, nil
+}
+
+// chartFileData returns the contents of the file at name in loadedChart.
+func chartFileData(loadedChart *chart.Chart, name string) ([]byte, error) {
+	for _, f := range loadedChart.Raw {
+		if f.Name == name {
+			return f.Data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q not found in chart %q", ErrValuesFile, name, loadedChart.Name())
+}
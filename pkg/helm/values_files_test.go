@@ -0,0 +1,310 @@
+package helm_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/offline"
+	"github.com/macropower/kclipper/pkg/paths"
+)
+
+func TestClientReadValuesFile(t *testing.T) {
+	t.Parallel()
+
+	repoRoot := t.TempDir()
+	pkgPath := filepath.Join(repoRoot, "pkg")
+	require.NoError(t, os.MkdirAll(pkgPath, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgPath, "values.yaml"), []byte("a: 1\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(repoRoot, "root.yaml"), []byte("b: 2\n"), 0o600))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/values.yaml" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, err := w.Write([]byte("c: 3\n"))
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := helm.MustNewClient(
+		paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()), "test",
+		helm.WithValuesFileURLs([]string{"127.0.0.1"}, 0),
+	)
+
+	tcs := map[string]struct {
+		err  error
+		want *helm.ValuesFile
+		file string
+	}{
+		"package relative": {
+			file: "values.yaml",
+			want: &helm.ValuesFile{Name: "values.yaml", Data: []byte("a: 1\n")},
+		},
+		"repository relative": {
+			file: "/root.yaml",
+			want: &helm.ValuesFile{Name: "/root.yaml", Data: []byte("b: 2\n")},
+		},
+		"outside repository": {
+			file: "../../root.yaml",
+			err:  paths.ErrResolvedOutsideRepo,
+		},
+		"chart relative": {
+			file: "chart://ci/../values-production.yaml",
+			want: &helm.ValuesFile{Name: "chart://ci/../values-production.yaml", ChartPath: "values-production.yaml"},
+		},
+		"outside chart": {
+			file: "chart://../values.yaml",
+			err:  helm.ErrValuesFile,
+		},
+		"url": {
+			file: srv.URL + "/values.yaml",
+			want: &helm.ValuesFile{Name: srv.URL + "/values.yaml", Data: []byte("c: 3\n")},
+		},
+		"url not found": {
+			file: srv.URL + "/missing.yaml",
+			err:  helm.ErrValuesFile,
+		},
+		"url scheme not allowed": {
+			file: "oci://example.com/values.yaml",
+			err:  paths.ErrURLSchemeNotAllowed,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := client.ReadValuesFile(t.Context(), tc.file, pkgPath, repoRoot)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("offline url", func(t *testing.T) {
+		t.Parallel()
+
+		offlineClient := helm.MustNewClient(
+			paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()), "test",
+			helm.WithOffline(true),
+			helm.WithValuesFileURLs([]string{"127.0.0.1"}, 0),
+		)
+
+		_, err := offlineClient.ReadValuesFile(t.Context(), srv.URL+"/values.yaml", pkgPath, repoRoot)
+		require.ErrorIs(t, err, offline.ErrOffline)
+	})
+}
+
+func TestClientReadValuesFileURLRestrictions(t *testing.T) {
+	t.Parallel()
+
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect.yaml" {
+			// Redirect to the same server, under a different host.
+			target := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1) + "/values.yaml"
+			http.Redirect(w, r, target, http.StatusFound)
+
+			return
+		}
+
+		data := []byte("c: 3\n")
+		if r.URL.Path == "/large.yaml" {
+			data = []byte("c: " + strings.Repeat("3", 2048) + "\n")
+		}
+
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	t.Cleanup(srv.Close)
+
+	tcs := map[string]struct {
+		err     error
+		file    string
+		hosts   []string
+		maxSize int64
+	}{
+		"no hosts": {
+			file: "/values.yaml",
+			err:  helm.ErrValuesFile,
+		},
+		"any host": {
+			file:  "/values.yaml",
+			hosts: []string{"*"},
+		},
+		"allowed host": {
+			file:  "/values.yaml",
+			hosts: []string{"example.com", "127.0.0.1"},
+		},
+		"host not allowed": {
+			file:  "/values.yaml",
+			hosts: []string{"example.com", "*.example.com"},
+			err:   helm.ErrValuesFile,
+		},
+		"redirect to allowed host": {
+			file:  "/redirect.yaml",
+			hosts: []string{"127.0.0.1", "localhost"},
+		},
+		"redirect to host not allowed": {
+			file:  "/redirect.yaml",
+			hosts: []string{"127.0.0.1"},
+			err:   helm.ErrValuesFile,
+		},
+		"within max size": {
+			file:    "/values.yaml",
+			hosts:   []string{"127.0.0.1"},
+			maxSize: 1024,
+		},
+		"larger than max size": {
+			file:    "/large.yaml",
+			hosts:   []string{"127.0.0.1"},
+			maxSize: 1024,
+			err:     helm.ErrValuesFile,
+		},
+		"larger than default max size": {
+			file:  "/large.yaml",
+			hosts: []string{"127.0.0.1"},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			client := helm.MustNewClient(
+				paths.NewStaticTempPaths(t.TempDir(), paths.NewBase64PathEncoder()), "test",
+				helm.WithValuesFileURLs(tc.hosts, tc.maxSize),
+			)
+
+			got, err := client.ReadValuesFile(t.Context(), srv.URL+tc.file, t.TempDir(), t.TempDir())
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotEmpty(t, got.Data)
+		})
+	}
+}
+
+func TestHelmChartValueFiles(t *testing.T) {
+	t.Parallel()
+
+	repoRoot := t.TempDir()
+	chartDir := filepath.Join(repoRoot, "charts", "test-chart")
+	require.NoError(t, os.MkdirAll(chartDir, 0o700))
+
+	files := map[string]string{
+		"Chart.yaml":             "apiVersion: v2\nname: test-chart\nversion: 0.1.0\n",
+		"values.yaml":            "image:\n  tag: v1\n  pullPolicy: IfNotPresent\nhosts: [a, b]\n",
+		"values-ingress.yaml":    "ingress:\n  hosts:\n  - host: a.example.com\n    path: /\n  - host: b.example.com\n    path: /\n",
+		"values-production.yaml": "image:\n  tag: v3\nreplicas: 3\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(chartDir, name), []byte(content), 0o600))
+	}
+
+	repoMgr := helmrepo.NewManager(helmrepo.WithAllowedPaths(repoRoot, repoRoot))
+	client := newTestClient(t)
+
+	tcs := map[string]struct {
+		values     map[string]any
+		set        *helm.SetValues
+		want       map[string]any
+		err        error
+		valueFiles []string
+	}{
+		"merged in order": {
+			valueFiles: []string{"/base.yaml", "chart://values-production.yaml"},
+			want: map[string]any{
+				"image":    map[string]any{"tag": "v3"},
+				"hosts":    []any{"c"},
+				"replicas": float64(3),
+			},
+		},
+		"values take precedence": {
+			valueFiles: []string{"chart://values-production.yaml"},
+			values:     map[string]any{"replicas": 5},
+			want: map[string]any{
+				"image":    map[string]any{"tag": "v3", "pullPolicy": "IfNotPresent"},
+				"hosts":    []any{"a", "b"},
+				"replicas": 5,
+			},
+		},
+		"set patches list items from values files": {
+			valueFiles: []string{"chart://values-ingress.yaml"},
+			values:     map[string]any{"replicas": 5},
+			set:        &helm.SetValues{Set: []string{"ingress.hosts[1].path=/b", "replicas=2"}},
+			want: map[string]any{
+				"image": map[string]any{"tag": "v1", "pullPolicy": "IfNotPresent"},
+				"hosts": []any{"a", "b"},
+				"ingress": map[string]any{"hosts": []any{
+					map[string]any{"host": "a.example.com", "path": "/"},
+					map[string]any{"host": "b.example.com", "path": "/b"},
+				}},
+				"replicas": int64(2),
+			},
+		},
+		"missing chart file": {
+			valueFiles: []string{"chart://values-missing.yaml"},
+			err:        helm.ErrValuesFile,
+		},
+	}
+
+	// The base file removes the chart's default pullPolicy with a null, and
+	// replaces its list of hosts.
+	require.NoError(t, os.WriteFile(filepath.Join(repoRoot, "base.yaml"),
+		[]byte("image:\n  tag: v2\n  pullPolicy: null\nhosts: [c]\n"), 0o600))
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			valueFiles := make([]*helm.ValuesFile, 0, len(tc.valueFiles))
+			for _, f := range tc.valueFiles {
+				vf, err := client.ReadValuesFile(t.Context(), f, repoRoot, repoRoot)
+				require.NoError(t, err)
+
+				valueFiles = append(valueFiles, vf)
+			}
+
+			c := helm.NewChart(client, repoMgr, &helm.TemplateOpts{
+				ChartName:    "test-chart",
+				RepoURL:      "./charts",
+				ValueFiles:   valueFiles,
+				ValuesObject: tc.values,
+				SetValues:    tc.set,
+			})
+
+			got, err := c.Values(t.Context())
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
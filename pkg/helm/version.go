@@ -0,0 +1,149 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v4/pkg/registry"
+
+	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/offline"
+)
+
+// ErrResolveVersion indicates that a version constraint could not be resolved
+// to a chart version.
+var ErrResolveVersion = errors.New("resolve chart version")
+
+// IsVersionConstraint returns true if version is a semver constraint (e.g.
+// `~6.7` or `>=1.2 <2`), rather than an exact version.
+func IsVersionConstraint(version string) bool {
+	if version == "" {
+		return false
+	}
+
+	_, err := semver.NewVersion(version)
+	if err == nil {
+		return false
+	}
+
+	_, err = semver.NewConstraint(version)
+
+	return err == nil
+}
+
+// resolveConstraint returns the version that the constraint resolves to. If
+// the [Client]'s [ChartLocker] has a version of the chart which satisfies the
+// constraint, it is used. Otherwise, the constraint is resolved against repo,
+// which is not possible in offline mode.
+func (c *Client) resolveConstraint(
+	ctx context.Context,
+	chart, repoURL, constraint string,
+	repo *helmrepo.Repo,
+) (string, error) {
+	if c.Lock != nil {
+		if version, ok := c.Lock.LockedVersion(chart, repoURL, constraint); ok {
+			slog.DebugContext(ctx, "using locked chart version",
+				slog.String("chart", chart),
+				slog.String("constraint", constraint),
+				slog.String("version", version),
+			)
+
+			return version, nil
+		}
+	}
+
+	if c.offline() {
+		return "", offline.CacheMissError{
+			Resource: "chart",
+			Name:     chart,
+			Version:  constraint,
+			Source:   repoURL,
+		}
+	}
+
+	version, err := c.resolveVersion(ctx, chart, constraint, repo)
+	if err != nil {
+		return "", err
+	}
+
+	slog.InfoContext(ctx, "resolved chart version",
+		slog.String("chart", chart),
+		slog.String("constraint", constraint),
+		slog.String("version", version),
+	)
+
+	return version, nil
+}
+
+// resolveVersion resolves the version constraint to the highest matching
+// version of chart available in repo. For OCI repositories, the tag list is
+// used. Otherwise, the repository's index is used.
+func (c *Client) resolveVersion(ctx context.Context, chart, constraint string, repo *helmrepo.Repo) (string, error) {
+	repo = c.withStoredCredentials(ctx, repo)
+
+	resolve := func() (string, error) {
+		if repo.IsOCI() {
+			return c.resolveOCIVersion(repo, constraint)
+		}
+
+		return c.resolveIndexVersion(ctx, chart, constraint, repo)
+	}
+
+	type result struct {
+		err     error
+		version string
+	}
+
+	// Helm's getters do not accept a context, so resolve in the background and
+	// stop waiting if ctx is cancelled.
+	done := make(chan result, 1)
+	go func() {
+		version, err := resolve()
+		done <- result{version: version, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", fmt.Errorf("%w: %w", ErrResolveVersion, ctx.Err())
+	case res := <-done:
+		if res.err != nil {
+			return "", fmt.Errorf("%w: %q: %w", ErrResolveVersion, constraint, res.err)
+		}
+
+		return res.version, nil
+	}
+}
+
+func (c *Client) resolveOCIVersion(repo *helmrepo.Repo, constraint string) (string, error) {
+	ref := strings.TrimPrefix(repo.URL.String(), registry.OCIScheme+"://")
+
+	tags, err := c.rc.Tags(ref)
+	if err != nil {
+		return "", fmt.Errorf("list tags for %s: %w", ref, err)
+	}
+
+	version, err := registry.GetTagMatchingVersionOrConstraint(tags, constraint)
+	if err != nil {
+		return "", fmt.Errorf("find tag in %s: %w", ref, err)
+	}
+
+	return version, nil
+}
+
+func (c *Client) resolveIndexVersion(ctx context.Context, chart, constraint string, repo *helmrepo.Repo) (string, error) {
+	index, err := c.repoIndex(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+
+	cv, err := index.Get(chart, constraint)
+	if err != nil {
+		return "", fmt.Errorf("find chart %q in %q: %w", chart, repo.URL.String(), err)
+	}
+
+	return cv.Version, nil
+}
@@ -0,0 +1,35 @@
+package helm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/macropower/kclipper/pkg/helm"
+)
+
+func TestIsVersionConstraint(t *testing.T) {
+	t.Parallel()
+
+	tcs := map[string]bool{
+		"":             false,
+		"1.2.3":        false,
+		"v1.2.3":       false,
+		"1.2.3-rc.1":   false,
+		"latest":       false,
+		"~6.7":         true,
+		"^1.2.3":       true,
+		"1.2.x":        true,
+		">=1.2 <2":     true,
+		">=1.2, <2":    true,
+		"1.2.3 || 2.x": true,
+		"*":            true,
+	}
+	for version, want := range tcs {
+		t.Run(version, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, want, helm.IsVersionConstraint(version))
+		})
+	}
+}
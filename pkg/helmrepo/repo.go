@@ -45,8 +45,16 @@ type Repo struct {
 	CAPath                paths.ResolvedFileOrDirectoryPath
 	TLSClientCertDataPath paths.ResolvedFileOrDirectoryPath
 	TLSClientCertKeyPath  paths.ResolvedFileOrDirectoryPath
-	InsecureSkipVerify    bool
-	PassCredentials       bool
+	// Provenance verification mode for charts pulled from this repository.
+	Verify VerifyMode
+	// Keyring used to verify chart provenance files.
+	Keyring paths.ResolvedFileOrDirectoryPath
+	// Root is the directory which paths relative to a local repository, e.g.
+	// `file://` dependencies of its charts, must stay within. It is only set
+	// for local repositories.
+	Root               string
+	InsecureSkipVerify bool
+	PassCredentials    bool
 }
 
 type RepoOpts struct {
@@ -58,8 +66,12 @@ type RepoOpts struct {
 	CAPath                string `json:"caPath,omitempty"`
 	TLSClientCertDataPath string `json:"tlsClientCertDataPath,omitempty"`
 	TLSClientCertKeyPath  string `json:"tlsClientCertKeyPath,omitempty"`
-	InsecureSkipVerify    bool   `json:"insecureSkipVerify"`
-	PassCredentials       bool   `json:"passCredentials"`
+	// Provenance verification mode for charts pulled from this repository.
+	Verify VerifyMode `json:"verify,omitempty"`
+	// Keyring used to verify chart provenance files.
+	Keyring            string `json:"keyring,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+	PassCredentials    bool   `json:"passCredentials"`
 }
 
 // IsLocal returns true if the repo URL is a local file path.
@@ -69,6 +81,20 @@ func (r *Repo) IsLocal() bool {
 	return !ok
 }
 
+// IsGit returns true if the repo URL uses a git+ scheme, e.g. git+https://.
+func (r *Repo) IsGit() bool {
+	u, ok := r.URL.URL()
+
+	return ok && strings.HasPrefix(u.Scheme, "git+")
+}
+
+// IsOCI returns true if the repo URL uses the oci:// scheme.
+func (r *Repo) IsOCI() bool {
+	u, ok := r.URL.URL()
+
+	return ok && u.Scheme == "oci"
+}
+
 func (r *RepoOpts) Validate() error {
 	if r.Name == "" {
 		return ErrRepoNameEmpty
@@ -97,7 +123,7 @@ type Manager struct {
 func NewManager(opt ...ManagerOpt) *Manager {
 	m := &Manager{
 		reposByName:       sync.Map{},
-		allowedURLSchemes: []string{"http", "https", "oci"},
+		allowedURLSchemes: []string{"http", "https", "oci", "git+https"},
 		currentPath:       ".",
 		repoRoot:          ".",
 	}
@@ -139,12 +165,18 @@ func (m *Manager) resolveRepo(repoOpts *RepoOpts) (*Repo, error) {
 		PassCredentials:    repoOpts.PassCredentials,
 	}
 
-	p, err := paths.ResolveFilePathOrURL(m.currentPath, m.repoRoot, repoOpts.URL, m.allowedURLSchemes)
+	repo.Verify, err = GetVerifyMode(string(repoOpts.Verify))
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := m.resolveURL(repoOpts.URL)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrFailedToResolveURL, err)
 	}
 
 	repo.URL = p
+	repo.Root = m.localRoot(p)
 
 	if repoOpts.CAPath != "" {
 		p, err := paths.ResolveFileOrDirectoryPath(m.currentPath, m.repoRoot, repoOpts.CAPath)
@@ -173,9 +205,40 @@ func (m *Manager) resolveRepo(repoOpts *RepoOpts) (*Repo, error) {
 		repo.TLSClientCertKeyPath = p
 	}
 
+	if repoOpts.Keyring != "" {
+		p, err := paths.ResolveFileOrDirectoryPath(m.currentPath, m.repoRoot, repoOpts.Keyring)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrFailedToResolveFile, err)
+		}
+
+		repo.Keyring = p
+	}
+
 	return repo, nil
 }
 
+// resolveURL resolves a repository URL or local path. URLs with the file://
+// scheme are treated as local paths, so that they are subject to the same
+// restrictions. E.g. `file://./charts` is equivalent to `./charts`.
+//
+//nolint:wrapcheck // Callers add context to the error.
+func (m *Manager) resolveURL(repoURL string) (paths.ResolvedFilePath, error) {
+	if after, ok := strings.CutPrefix(repoURL, "file://"); ok {
+		repoURL = after
+	}
+
+	return paths.ResolveFilePathOrURL(m.currentPath, m.repoRoot, repoURL, m.allowedURLSchemes)
+}
+
+// localRoot returns the [Repo.Root] for the resolved repository URL p.
+func (m *Manager) localRoot(p paths.ResolvedFilePath) string {
+	if _, ok := p.URL(); ok {
+		return ""
+	}
+
+	return m.repoRoot
+}
+
 // Add uses [RepoOpts] to create and add a new [Repo] to the [Manager].
 // An error is returned if the [Repo] could not be generated, or if a [Repo]
 // with the same Name and/or URL already exists.
@@ -238,7 +301,7 @@ func (m *Manager) GetByURL(repoURL string) (*Repo, error) {
 		return repo, nil
 	}
 
-	p, err := paths.ResolveFilePathOrURL(m.currentPath, m.repoRoot, repoURL, m.allowedURLSchemes)
+	p, err := m.resolveURL(repoURL)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrFailedToResolveURL, err)
 	}
@@ -246,5 +309,6 @@ func (m *Manager) GetByURL(repoURL string) (*Repo, error) {
 	return &Repo{
 		Name: repoURL,
 		URL:  p,
+		Root: m.localRoot(p),
 	}, nil
 }
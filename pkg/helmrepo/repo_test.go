@@ -135,6 +135,24 @@ func TestLocalRepo(t *testing.T) {
 	assert.True(t, retrievedRepo.IsLocal())
 }
 
+func TestFileURLRepo(t *testing.T) {
+	t.Parallel()
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	manager := helmrepo.NewManager(helmrepo.WithAllowedPaths(cwd, filepath.Dir(cwd)))
+
+	retrievedRepo, err := manager.Get("file://./testdata")
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(cwd, "testdata"), retrievedRepo.URL.String())
+	assert.True(t, retrievedRepo.IsLocal())
+
+	_, err = manager.Get("file://../../example")
+	require.ErrorIs(t, err, paths.ErrResolvedOutsideRepo)
+}
+
 func TestOCIRepo(t *testing.T) {
 	t.Parallel()
 
@@ -155,6 +173,24 @@ func TestOCIRepo(t *testing.T) {
 	assert.False(t, retrievedRepo.IsLocal())
 }
 
+func TestGitRepo(t *testing.T) {
+	t.Parallel()
+
+	manager := helmrepo.NewManager()
+
+	retrievedRepo, err := manager.Get("git+https://example.com/org/repo.git//charts/foo?ref=v1.2.3")
+	require.NoError(t, err)
+
+	assert.Equal(t, "git+https://example.com/org/repo.git//charts/foo?ref=v1.2.3", retrievedRepo.URL.String())
+	assert.True(t, retrievedRepo.IsGit())
+	assert.False(t, retrievedRepo.IsLocal())
+	assert.False(t, retrievedRepo.IsOCI())
+
+	// Local repositories can only be cloned if explicitly allowed.
+	_, err = manager.Get("git+file:///tmp/repo")
+	require.ErrorIs(t, err, paths.ErrURLSchemeNotAllowed)
+}
+
 func TestInvalidRepo(t *testing.T) {
 	t.Parallel()
 
@@ -229,6 +265,23 @@ func TestInvalidRepo(t *testing.T) {
 			},
 			err: paths.ErrResolvedOutsideRepo,
 		},
+		"invalid keyring": {
+			repo: &helmrepo.RepoOpts{
+				Name:    "invalid-keyring",
+				URL:     "https://example.com/charts",
+				Verify:  helmrepo.VerifyModeAlways,
+				Keyring: "../../example",
+			},
+			err: paths.ErrResolvedOutsideRepo,
+		},
+		"invalid verify mode": {
+			repo: &helmrepo.RepoOpts{
+				Name:   "invalid-verify",
+				URL:    "https://example.com/charts",
+				Verify: "sometimes",
+			},
+			err: helmrepo.ErrInvalidVerifyMode,
+		},
 	}
 	for name, tc := range tcs {
 		t.Run(name, func(t *testing.T) {
@@ -257,6 +310,8 @@ func TestAllOpts(t *testing.T) {
 		CAPath:                "ca.pem",
 		TLSClientCertDataPath: "client.pem",
 		TLSClientCertKeyPath:  "client.key",
+		Verify:                "if_possible",
+		Keyring:               "pubring.gpg",
 		InsecureSkipVerify:    true,
 		PassCredentials:       true,
 	}
@@ -273,6 +328,8 @@ func TestAllOpts(t *testing.T) {
 	assert.Equal(t, filepath.Join(cwd, "ca.pem"), retrievedRepo.CAPath.String())
 	assert.Equal(t, filepath.Join(cwd, "client.pem"), retrievedRepo.TLSClientCertDataPath.String())
 	assert.Equal(t, filepath.Join(cwd, "client.key"), retrievedRepo.TLSClientCertKeyPath.String())
+	assert.Equal(t, helmrepo.VerifyModeIfPossible, retrievedRepo.Verify)
+	assert.Equal(t, filepath.Join(cwd, "pubring.gpg"), retrievedRepo.Keyring.String())
 	assert.True(t, retrievedRepo.InsecureSkipVerify)
 	assert.True(t, retrievedRepo.PassCredentials)
 }
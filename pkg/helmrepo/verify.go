@@ -0,0 +1,57 @@
+package helmrepo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidVerifyMode indicates that a [VerifyMode] is not recognized.
+var ErrInvalidVerifyMode = errors.New("invalid verify mode")
+
+// VerifyMode defines whether the provenance (.prov) files of charts pulled
+// from a [Repo] are verified.
+type VerifyMode string
+
+const (
+	// VerifyModeDefault defers to the client's default verify mode.
+	VerifyModeDefault VerifyMode = ""
+	// VerifyModeNever skips provenance verification.
+	VerifyModeNever VerifyMode = "NEVER"
+	// VerifyModeIfPossible verifies charts which have a provenance file, and
+	// allows charts without one.
+	VerifyModeIfPossible VerifyMode = "IF_POSSIBLE"
+	// VerifyModeAlways requires all charts to have a valid provenance file.
+	VerifyModeAlways VerifyMode = "ALWAYS"
+)
+
+var (
+	// VerifyModeEnum lists all valid verify modes.
+	VerifyModeEnum = []any{
+		VerifyModeNever,
+		VerifyModeIfPossible,
+		VerifyModeAlways,
+	}
+
+	verifyModes = map[string]VerifyMode{
+		string(VerifyModeDefault):    VerifyModeDefault,
+		string(VerifyModeNever):      VerifyModeNever,
+		string(VerifyModeIfPossible): VerifyModeIfPossible,
+		string(VerifyModeAlways):     VerifyModeAlways,
+	}
+)
+
+// GetVerifyMode returns the [VerifyMode] matching the given string. Matching
+// is case-insensitive.
+func GetVerifyMode(s string) (VerifyMode, error) {
+	if vm, ok := verifyModes[strings.TrimSpace(strings.ToUpper(s))]; ok {
+		return vm, nil
+	}
+
+	return VerifyModeDefault, fmt.Errorf("%w: %q, expected one of %v", ErrInvalidVerifyMode, s, VerifyModeEnum)
+}
+
+// Enabled returns true if charts are verified in this mode.
+func (m VerifyMode) Enabled() bool {
+	return m == VerifyModeIfPossible || m == VerifyModeAlways
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/iancoleman/strcase"
 
+	"github.com/macropower/kclipper/pkg/helm"
 	"github.com/macropower/kclipper/pkg/schema"
 )
 
@@ -40,6 +41,14 @@ func (c *Chart) GenerateKCL(w io.Writer) error {
 		"releaseName", c.ReleaseName != "",
 		schema.WithDefault(c.ReleaseName),
 	)
+	js.SetOrRemoveProperty(
+		"kubeVersion", c.KubeVersion != "",
+		schema.WithDefault(c.KubeVersion),
+	)
+	js.SetOrRemoveProperty(
+		"apiVersions", len(c.APIVersions) > 0,
+		schema.WithDefault(c.APIVersions),
+	)
 	js.SetOrRemoveProperty(
 		"skipCRDs", c.SkipCRDs,
 		schema.WithDefault(c.SkipCRDs),
@@ -57,15 +66,40 @@ func (c *Chart) GenerateKCL(w io.Writer) error {
 		schema.WithDefault(c.SchemaValidator),
 		schema.WithEnum(schema.ValidatorTypeEnum),
 	)
+	js.SetOrRemoveProperty(
+		"sort", c.Sort != helm.SortOrderDefault,
+		schema.WithDefault(c.Sort),
+		schema.WithEnum(helm.SortOrderEnum),
+	)
 	js.SetOrRemoveProperty(
 		"repositories", len(c.Repositories) > 0,
 		schema.WithDefault(c.Repositories),
 		schema.WithType("null"),
 		schema.WithNoContent(),
 	)
+	js.SetOrRemoveProperty(
+		"set", len(c.Set) > 0,
+		schema.WithDefault(c.Set),
+	)
+	js.SetOrRemoveProperty(
+		"setString", len(c.SetString) > 0,
+		schema.WithDefault(c.SetString),
+	)
+	js.SetOrRemoveProperty(
+		"setFile", len(c.SetFile) > 0,
+		schema.WithDefault(c.SetFile),
+	)
 
 	js.RemoveProperty("valueFiles")
 	js.RemoveProperty("postRenderer")
+	js.RemoveProperty("lookups")
+	js.RemoveProperty("commonLabels")
+	js.RemoveProperty("commonAnnotations")
+	js.RemoveProperty("forceNamespace")
+	js.RemoveProperty("clusterScopedKinds")
+	js.RemoveProperty("createNamespace")
+	js.RemoveProperty("argocdAppName")
+	js.RemoveProperty("timeout")
 
 	err = js.GenerateKCL(w, genOptInheritHelmChart, genOptFixValues, genOptFixChartRepo)
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 	"github.com/iancoleman/strcase"
 
 	"github.com/macropower/kclipper/pkg/crd"
+	"github.com/macropower/kclipper/pkg/helm"
 	"github.com/macropower/kclipper/pkg/kclautomation"
 	"github.com/macropower/kclipper/pkg/schema"
 )
@@ -87,6 +88,14 @@ func (c *ChartConfig) GenerateKCL(w io.Writer) error {
 		"releaseName", c.ReleaseName != "",
 		schema.WithDefault(c.ReleaseName),
 	)
+	js.SetOrRemoveProperty(
+		"kubeVersion", c.KubeVersion != "",
+		schema.WithDefault(c.KubeVersion),
+	)
+	js.SetOrRemoveProperty(
+		"apiVersions", len(c.APIVersions) > 0,
+		schema.WithDefault(c.APIVersions),
+	)
 	js.SetOrRemoveProperty(
 		"skipCRDs", c.SkipCRDs,
 		schema.WithDefault(c.SkipCRDs),
@@ -112,6 +121,11 @@ func (c *ChartConfig) GenerateKCL(w io.Writer) error {
 		schema.WithDefault(c.SchemaValidator),
 		schema.WithEnum(schema.ValidatorTypeEnum),
 	)
+	js.SetOrRemoveProperty(
+		"sort", c.Sort != helm.SortOrderDefault,
+		schema.WithDefault(c.Sort),
+		schema.WithEnum(helm.SortOrderEnum),
+	)
 	js.SetOrRemoveProperty(
 		"schemaGenerator", c.SchemaGenerator != schema.DefaultGeneratorType,
 		schema.WithDefault(c.SchemaGenerator),
@@ -128,6 +142,18 @@ func (c *ChartConfig) GenerateKCL(w io.Writer) error {
 		schema.WithType("null"),
 		schema.WithNoContent(),
 	)
+	js.SetOrRemoveProperty(
+		"set", len(c.Set) > 0,
+		schema.WithDefault(c.Set),
+	)
+	js.SetOrRemoveProperty(
+		"setString", len(c.SetString) > 0,
+		schema.WithDefault(c.SetString),
+	)
+	js.SetOrRemoveProperty(
+		"setFile", len(c.SetFile) > 0,
+		schema.WithDefault(c.SetFile),
+	)
 	js.SetOrRemoveProperty(
 		"values", c.Values != nil,
 		schema.WithDefault(c.Values),
@@ -149,11 +175,13 @@ func (c *ChartConfig) ToAutomation() kclautomation.Automation {
 		"targetRevision":  kclautomation.NewString(c.TargetRevision),
 		"releaseName":     kclautomation.NewString(c.ReleaseName),
 		"namespace":       kclautomation.NewString(c.Namespace),
+		"kubeVersion":     kclautomation.NewString(c.KubeVersion),
 		"skipCRDs":        kclautomation.NewBool(c.SkipCRDs),
 		"skipHooks":       kclautomation.NewBool(c.SkipHooks),
 		"passCredentials": kclautomation.NewBool(c.PassCredentials),
 		"schemaPath":      kclautomation.NewString(c.SchemaPath),
 		"schemaValidator": kclautomation.NewString(string(c.SchemaValidator)),
+		"sort":            kclautomation.NewString(string(c.Sort)),
 		"schemaGenerator": kclautomation.NewString(string(c.SchemaGenerator)),
 		"crdGenerator":    kclautomation.NewString(string(c.CRDGenerator)),
 	}
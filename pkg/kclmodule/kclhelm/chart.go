@@ -11,8 +11,33 @@ import (
 type Chart struct {
 	// Lambda function to modify the Helm template output. Evaluated for each resource in the Helm template output.
 	PostRenderer any `json:"postRenderer,omitempty"`
-	// Helm value files to be passed to Helm template.
+	// Maximum time to spend pulling and rendering the chart, e.g. `5m`.
+	// Defaults to the `ARGOCD_EXEC_TIMEOUT` environment variable, or `60s`.
+	Timeout string `json:"timeout,omitempty"`
+	// Helm value files to be passed to Helm template, merged in order like Helm's `--values`. Paths are
+	// relative to the KCL package, or to the repository root if they start with `/`. Files in the chart
+	// can be used with a `chart://` prefix, e.g. `chart://values-production.yaml`, and remote files with
+	// an `http://` or `https://` URL.
 	ValueFiles []string `json:"valueFiles,omitempty"`
+	// Kubernetes resources to be returned by Helm's `lookup` template function,
+	// in place of resources from a cluster. Matched by apiVersion, kind, namespace, and name.
+	Lookups []any `json:"lookups,omitempty"`
+	// Labels to add to all rendered resources, replacing labels with the same keys.
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+	// Annotations to add to all rendered resources, replacing annotations with the same keys.
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+	// Set to `True` to set the namespace of all namespaced resources to `namespace`.
+	// Note that custom resources are assumed to be namespaced, unless their CRD is rendered by the
+	// chart or they are listed in `clusterScopedKinds`.
+	ForceNamespace bool `json:"forceNamespace,omitempty"`
+	// Additional cluster-scoped kinds, in the format `Kind.group`, e.g. `ClusterIssuer.cert-manager.io`.
+	// They are never given a namespace by `forceNamespace` or `argocdAppName`.
+	ClusterScopedKinds []string `json:"clusterScopedKinds,omitempty"`
+	// Set to `True` to add a Namespace resource for `namespace`, unless the chart renders one.
+	CreateNamespace bool `json:"createNamespace,omitempty"`
+	// Argo CD application name. If set, Argo CD's `argocd.argoproj.io/tracking-id`
+	// annotation is added to all rendered resources.
+	ArgoCDAppName string `json:"argocdAppName,omitempty"`
 }
 
 func (c *Chart) GenerateKCL(w io.Writer) error {
@@ -21,7 +46,7 @@ func (c *Chart) GenerateKCL(w io.Writer) error {
 		return fmt.Errorf("reflect schema: %w", err)
 	}
 
-	err = js.GenerateKCL(w, genOptInheritChartBase, genOptFixChartRepo, genOptFixPostRenderer)
+	err = js.GenerateKCL(w, genOptInheritChartBase, genOptFixChartRepo, genOptFixPostRenderer, genOptFixLookups)
 	if err != nil {
 		return fmt.Errorf("convert JSON Schema to KCL schema: %w", err)
 	}
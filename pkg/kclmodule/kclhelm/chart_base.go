@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/macropower/kclipper/pkg/helm"
 	"github.com/macropower/kclipper/pkg/schema"
 )
 
@@ -15,16 +16,36 @@ type ChartBase struct {
 	Chart string `json:"chart"`
 	// URL of the Helm chart repository.
 	RepoURL string `json:"repoURL"`
-	// Semver tag for the chart's version. May be omitted for local charts.
+	// Semver tag for the chart's version, or a constraint such as `~6.7`, which resolves to the
+	// highest matching version. May be omitted for local charts. OCI charts may be pinned to a
+	// manifest digest, e.g. `1.2.3@sha256:...`, or `sha256:...` alone.
 	TargetRevision string `json:"targetRevision,omitempty"`
 	// Helm release name to use. If omitted the chart name will be used.
 	ReleaseName string `json:"releaseName,omitempty"`
 	// Optional namespace to template with.
 	Namespace string `json:"namespace,omitempty"`
+	// Kubernetes version to template with (`.Capabilities.KubeVersion`).
+	// Defaults to the `KUBE_VERSION` environment variable.
+	KubeVersion string `json:"kubeVersion,omitempty"`
 	// Validator to use for the Values schema.
 	SchemaValidator schema.ValidatorType `json:"schemaValidator,omitempty"`
+	// Order of the rendered resources. `INSTALL` uses Helm's install order, and `KIND` sorts
+	// by kind. Both then sort by namespace and name. By default, the rendered order is kept.
+	Sort helm.SortOrder `json:"sort,omitempty"`
+	// Kubernetes API versions to template with (`.Capabilities.APIVersions`).
+	// Defaults to the `KUBE_API_VERSIONS` environment variable.
+	APIVersions []string `json:"apiVersions,omitempty"`
 	// Helm chart repositories.
 	Repositories []ChartRepo `json:"repositories,omitempty"`
+	// Helm-style value overrides (Helm's `--set`), applied on top of values, e.g.
+	// `image.tag=v2` or `ingress.hosts[0].host=example.com`.
+	Set []string `json:"set,omitempty"`
+	// Helm-style value overrides whose values are always strings (Helm's `--set-string`).
+	SetString []string `json:"setString,omitempty"`
+	// Helm-style value overrides whose values are read from files (Helm's `--set-file`),
+	// e.g. `tls.crt=certs/tls.crt`. Paths are relative to the KCL package, or to the
+	// repository root if they start with `/`.
+	SetFile []string `json:"setFile,omitempty"`
 	// Set to `True` to skip the custom resource definition installation step (Helm's `--skip-crds`).
 	SkipCRDs bool `json:"skipCRDs,omitempty"`
 	// Set to `True` to skip templating Helm hooks (similar to Helm's `--no-hooks`).
@@ -40,6 +61,7 @@ func (c *ChartBase) GenerateKCL(w io.Writer) error {
 	}
 
 	js.SetProperty("schemaValidator", schema.WithEnum(schema.ValidatorTypeEnum))
+	js.SetProperty("sort", schema.WithEnum(helm.SortOrderEnum))
 	js.SetProperty("repositories", schema.WithType("null"), schema.WithNoContent())
 
 	err = js.GenerateKCL(w, genOptFixChartRepo)
@@ -7,11 +7,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/iancoleman/strcase"
 
 	"github.com/macropower/kclipper/pkg/helmrepo"
 	"github.com/macropower/kclipper/pkg/kclautomation"
+	"github.com/macropower/kclipper/pkg/paths"
 	"github.com/macropower/kclipper/pkg/schema"
 )
 
@@ -26,6 +28,12 @@ type ChartRepo struct {
 	UsernameEnv string `json:"usernameEnv,omitempty"`
 	// Basic authentication password environment variable.
 	PasswordEnv string `json:"passwordEnv,omitempty"`
+	// Basic authentication username file path.
+	UsernameFile string `json:"usernameFile,omitempty"`
+	// Basic authentication password file path.
+	PasswordFile string `json:"passwordFile,omitempty"`
+	// Access token file path. The token is used as the basic authentication password.
+	TokenFile string `json:"tokenFile,omitempty"`
 
 	// CA file path.
 	CAPath string `json:"caPath,omitempty"`
@@ -34,6 +42,13 @@ type ChartRepo struct {
 	// TLS client certificate key path.
 	TLSClientCertKeyPath string `json:"tlsClientCertKeyPath,omitempty"`
 
+	// Chart provenance verification mode. `ALWAYS` requires a valid provenance file for every chart,
+	// and `IF_POSSIBLE` only verifies charts that have one. Defaults to the `KCLIPPER_HELM_VERIFY`
+	// environment variable, or `NEVER`.
+	Verify helmrepo.VerifyMode `json:"verify,omitempty"`
+	// Keyring file path used to verify chart provenance files.
+	Keyring string `json:"keyring,omitempty"`
+
 	// Set to `True` to skip SSL certificate verification.
 	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
 	// Set to `True` to allow credentials to be used in chart dependencies defined
@@ -50,6 +65,14 @@ func (c *ChartRepo) Validate() error {
 		return errors.New("url is required")
 	}
 
+	if c.UsernameEnv != "" && c.UsernameFile != "" {
+		return errors.New("only one of usernameEnv and usernameFile can be set")
+	}
+
+	if countSet(c.PasswordEnv, c.PasswordFile, c.TokenFile) > 1 {
+		return errors.New("only one of passwordEnv, passwordFile and tokenFile can be set")
+	}
+
 	return nil
 }
 
@@ -63,6 +86,8 @@ func (c *ChartRepo) GenerateKCL(w io.Writer) error {
 		return fmt.Errorf("reflect schema: %w", err)
 	}
 
+	js.SetProperty("verify", schema.WithEnum(helmrepo.VerifyModeEnum))
+
 	err = js.GenerateKCL(w)
 	if err != nil {
 		return fmt.Errorf("convert JSON Schema to KCL schema: %w", err)
@@ -91,13 +116,24 @@ func (c *ChartRepo) FromMap(m map[string]any) error {
 	return nil
 }
 
-func (c *ChartRepo) GetHelmRepo() (*helmrepo.RepoOpts, error) {
+// GetHelmRepo returns the [helmrepo.RepoOpts] for the [ChartRepo], reading
+// credentials from the configured environment variables and files. Files are
+// resolved relative to currentPath (or to repoRoot, if they start with "/"),
+// and must be within repoRoot. Errors never include the contents of a file.
+func (c *ChartRepo) GetHelmRepo(currentPath, repoRoot string) (*helmrepo.RepoOpts, error) {
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+
 	repo := &helmrepo.RepoOpts{
 		Name:                  c.Name,
 		URL:                   c.URL,
 		CAPath:                c.CAPath,
 		TLSClientCertDataPath: c.TLSClientCertDataPath,
 		TLSClientCertKeyPath:  c.TLSClientCertKeyPath,
+		Verify:                c.Verify,
+		Keyring:               c.Keyring,
 		InsecureSkipVerify:    c.InsecureSkipVerify,
 		PassCredentials:       c.PassCredentials,
 	}
@@ -120,18 +156,80 @@ func (c *ChartRepo) GetHelmRepo() (*helmrepo.RepoOpts, error) {
 		repo.Password = password
 	}
 
+	if c.UsernameFile != "" {
+		username, err := readCredentialFile(currentPath, repoRoot, c.UsernameFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get username: %w", err)
+		}
+
+		repo.Username = username
+	}
+
+	if c.PasswordFile != "" {
+		password, err := readCredentialFile(currentPath, repoRoot, c.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get password: %w", err)
+		}
+
+		repo.Password = password
+	}
+
+	if c.TokenFile != "" {
+		token, err := readCredentialFile(currentPath, repoRoot, c.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+
+		repo.Password = token
+	}
+
 	return repo, nil
 }
 
+// readCredentialFile reads the credential in file, which is resolved like the
+// repository's other file paths. Trailing newlines are removed, since secrets
+// are commonly written with one.
+func readCredentialFile(currentPath, repoRoot, file string) (string, error) {
+	p, err := paths.ResolveFileOrDirectoryPath(currentPath, repoRoot, file)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", helmrepo.ErrFailedToResolveFile, err)
+	}
+
+	data, err := os.ReadFile(p.String())
+	if err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// countSet returns the number of non-empty values.
+func countSet(values ...string) int {
+	n := 0
+
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+
+	return n
+}
+
 func (c *ChartRepo) ToAutomation() kclautomation.Automation {
 	return kclautomation.Automation{
 		"name":                  kclautomation.NewString(c.Name),
 		"url":                   kclautomation.NewString(c.URL),
 		"usernameEnv":           kclautomation.NewString(c.UsernameEnv),
 		"passwordEnv":           kclautomation.NewString(c.PasswordEnv),
+		"usernameFile":          kclautomation.NewString(c.UsernameFile),
+		"passwordFile":          kclautomation.NewString(c.PasswordFile),
+		"tokenFile":             kclautomation.NewString(c.TokenFile),
 		"caPath":                kclautomation.NewString(c.CAPath),
 		"tlsClientCertDataPath": kclautomation.NewString(c.TLSClientCertDataPath),
 		"tlsClientCertKeyPath":  kclautomation.NewString(c.TLSClientCertKeyPath),
+		"verify":                kclautomation.NewString(string(c.Verify)),
+		"keyring":               kclautomation.NewString(c.Keyring),
 		"insecureSkipVerify":    kclautomation.NewBool(c.InsecureSkipVerify),
 		"passCredentials":       kclautomation.NewBool(c.PassCredentials),
 	}
@@ -0,0 +1,118 @@
+package kclhelm_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/helmrepo"
+	"github.com/macropower/kclipper/pkg/kclmodule/kclhelm"
+)
+
+func TestChartRepoGetHelmRepo(t *testing.T) {
+	t.Parallel()
+
+	repoRoot := t.TempDir()
+	pkgPath := filepath.Join(repoRoot, "pkg")
+	require.NoError(t, os.MkdirAll(filepath.Join(repoRoot, "secrets"), 0o700))
+	require.NoError(t, os.MkdirAll(pkgPath, 0o700))
+
+	secrets := map[string]string{
+		"username": "user\n",
+		"password": "hunter2\r\n",
+		"token":    "t0ken",
+	}
+	for name, content := range secrets {
+		require.NoError(t, os.WriteFile(filepath.Join(repoRoot, "secrets", name), []byte(content), 0o600))
+	}
+
+	outside := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(outside, []byte("outside-secret"), 0o600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(repoRoot, "secrets", "link")))
+
+	tcs := map[string]struct {
+		repo         kclhelm.ChartRepo
+		wantErr      error
+		wantUsername string
+		wantPassword string
+		wantAnyErr   bool
+	}{
+		"username and password files": {
+			repo: kclhelm.ChartRepo{
+				UsernameFile: "../secrets/username",
+				PasswordFile: "/secrets/password",
+			},
+			wantUsername: "user",
+			wantPassword: "hunter2",
+		},
+		"token file": {
+			repo: kclhelm.ChartRepo{
+				UsernameFile: "/secrets/username",
+				TokenFile:    "/secrets/token",
+			},
+			wantUsername: "user",
+			wantPassword: "t0ken",
+		},
+		"missing file": {
+			repo:       kclhelm.ChartRepo{PasswordFile: "/secrets/missing"},
+			wantAnyErr: true,
+		},
+		"outside repository": {
+			repo:    kclhelm.ChartRepo{PasswordFile: "../../password"},
+			wantErr: helmrepo.ErrFailedToResolveFile,
+		},
+		"symlink outside repository": {
+			repo:    kclhelm.ChartRepo{PasswordFile: "/secrets/link"},
+			wantErr: helmrepo.ErrFailedToResolveFile,
+		},
+		"file is a directory": {
+			repo:       kclhelm.ChartRepo{TokenFile: "/secrets"},
+			wantAnyErr: true,
+		},
+		"password env and file": {
+			repo: kclhelm.ChartRepo{
+				PasswordEnv:  "REPO_PASSWORD",
+				PasswordFile: "/secrets/password",
+			},
+			wantAnyErr: true,
+		},
+		"password and token files": {
+			repo: kclhelm.ChartRepo{
+				PasswordFile: "/secrets/password",
+				TokenFile:    "/secrets/token",
+			},
+			wantAnyErr: true,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.repo.Name = "test"
+			tc.repo.URL = "https://example.com/charts"
+
+			got, err := tc.repo.GetHelmRepo(pkgPath, repoRoot)
+			if tc.wantErr != nil || tc.wantAnyErr {
+				require.Error(t, err)
+
+				if tc.wantErr != nil {
+					require.ErrorIs(t, err, tc.wantErr)
+				}
+
+				for _, secret := range []string{"hunter2", "t0ken", "outside-secret"} {
+					assert.NotContains(t, err.Error(), secret)
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantUsername, got.Username)
+			assert.Equal(t, tc.wantPassword, got.Password)
+		})
+	}
+}
@@ -14,6 +14,8 @@ const (
 	valueInferenceKCLType string = "ValueInferenceConfig"
 	postRendererKCLName   string = "postRenderer"
 	postRendererKCLType   string = "(Resource) -> Resource"
+	lookupsKCLName        string = "lookups"
+	lookupsKCLType        string = "[Resource]"
 )
 
 var (
@@ -21,9 +23,11 @@ var (
 	repositoriesRegexp     = regexp.MustCompile(`(\s+` + repositoriesKCLName + `\??\s*:\s+)any(.*)`)
 	valueInferenceRegexp   = regexp.MustCompile(`(\s+` + valueInferenceKCLName + `\??\s*:\s+)any(.*)`)
 	postRendererRegexp     = regexp.MustCompile(`(\s+` + postRendererKCLName + `\??\s*:\s+)any(.*)`)
+	lookupsRegexp          = regexp.MustCompile(`(\s+` + lookupsKCLName + `\??\s*:\s+)\[any\](.*)`)
 
 	genOptInheritChartBase  = schema.Replace(schemaDefinitionRegexp, "schema ${1}("+chartBaseKCLType+"):${2}")
 	genOptFixChartRepo      = schema.Replace(repositoriesRegexp, "${1}"+repositoriesKCLType+"${2}")
 	genOptFixValueInference = schema.Replace(valueInferenceRegexp, "${1}"+valueInferenceKCLType+"${2}")
 	genOptFixPostRenderer   = schema.Replace(postRendererRegexp, "${1}"+postRendererKCLType+"${2}")
+	genOptFixLookups        = schema.Replace(lookupsRegexp, "${1}"+lookupsKCLType+"${2}")
 )
@@ -0,0 +1,84 @@
+package helm
+
+import (
+	"fmt"
+	"log/slog"
+
+	"kcl-lang.io/kcl-go/pkg/plugin"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/kclplugin/plugins"
+)
+
+// chartInfoBody implements the "chart_info" method of [Plugin].
+func chartInfoBody(args *plugin.MethodArgs) (*plugin.MethodResult, error) {
+	logger := slog.With(
+		slog.String("plugin", "helm"),
+		slog.String("method", "chart_info"),
+	)
+	logger.Debug("invoking kcl plugin")
+
+	safeArgs := plugins.SafeMethodArgs{Args: args}
+
+	err := validateChartArgs(safeArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	chartName := args.StrKwArg(argChart)
+	logger = logger.With(
+		slog.String(argChart, chartName),
+	)
+
+	repoURL := args.StrKwArg(argRepoURL)
+	targetRevision := safeArgs.StrKwArg(argTargetRevision, "")
+	repos := safeArgs.ListKwArg(argRepositories, []any{})
+
+	env, err := getEnvironment(safeArgs.StrKwArg(argTimeout, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("set arguments",
+		slog.String(argRepoURL, repoURL),
+		slog.String(argTargetRevision, targetRevision),
+		slog.String("project", env.project),
+		slog.String("cwd", env.cwd),
+		slog.String("pkg_path", env.pkgPath),
+		slog.String("repo_root", env.repoRoot),
+		slog.String("timeout", env.timeout.String()),
+	)
+
+	repoMgr, err := env.newRepoManager(repos)
+	if err != nil {
+		return nil, err
+	}
+
+	helmClient, err := env.sharedClient()
+	if err != nil {
+		return nil, err
+	}
+
+	helmChart := helm.NewChart(helmClient, repoMgr, &helm.TemplateOpts{
+		ChartName:      chartName,
+		TargetRevision: targetRevision,
+		RepoURL:        repoURL,
+		Timeout:        env.timeout,
+	})
+
+	logger.Info("load helm chart")
+
+	info, err := helmChart.Info(getContext())
+	if err != nil {
+		return nil, fmt.Errorf("get info for %q: %w", chartName, err)
+	}
+
+	result, err := info.ToMap()
+	if err != nil {
+		return nil, fmt.Errorf("get info for %q: %w", chartName, err)
+	}
+
+	logger.Debug("returning results")
+
+	return &plugin.MethodResult{V: result}, nil
+}
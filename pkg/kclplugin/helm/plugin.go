@@ -7,11 +7,15 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"kcl-lang.io/kcl-go/pkg/plugin"
 
+	"github.com/macropower/kclipper/pkg/chartlock"
 	"github.com/macropower/kclipper/pkg/helm"
 	"github.com/macropower/kclipper/pkg/helmrepo"
 	"github.com/macropower/kclipper/pkg/kclmodule/kclhelm"
@@ -32,6 +36,33 @@ const (
 	argPassCredentials      string = "pass_credentials"
 	argRepositories         string = "repositories"
 	argValues               string = "values"
+	argKubeVersion          string = "kube_version"
+	argAPIVersions          string = "api_versions"
+	argLookups              string = "lookups"
+	argSort                 string = "sort"
+	argTimeout              string = "timeout"
+	argCharts               string = "charts"
+	argKey                  string = "key"
+	argConcurrency          string = "concurrency"
+	argCommonLabels         string = "common_labels"
+	argCommonAnnotations    string = "common_annotations"
+	argForceNamespace       string = "force_namespace"
+	argClusterScopedKinds   string = "cluster_scoped_kinds"
+	argCreateNamespace      string = "create_namespace"
+	argArgoCDAppName        string = "argocd_app_name"
+	argSet                  string = "set"
+	argSetString            string = "set_string"
+	argSetFile              string = "set_file"
+	argValueFiles           string = "value_files"
+)
+
+var (
+	rootCtx   = context.Background()
+	rootCtxMu sync.RWMutex
+
+	// clients holds the [helm.Client]s shared by all [Plugin] method calls.
+	clients   = map[clientKey]*helm.Client{}
+	clientsMu sync.Mutex
 )
 
 // Register registers the helm [Plugin] with the KCL plugin system.
@@ -39,178 +70,620 @@ func Register() {
 	plugin.RegisterPlugin(Plugin)
 }
 
-// Plugin is the KCL plugin that exposes Helm template functionality.
+// SetContext sets the context that [Plugin] method calls run under. When ctx
+// is cancelled (e.g. on interrupt), in-flight chart pulls are cancelled.
+func SetContext(ctx context.Context) {
+	rootCtxMu.Lock()
+	defer rootCtxMu.Unlock()
+
+	rootCtx = ctx
+}
+
+// Close closes the [helm.Client]s shared by [Plugin] method calls, removing
+// their temporary files. It should be called before the process exits.
+func Close() error {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	var errs error
+
+	for key, c := range clients {
+		errs = errors.Join(errs, c.Close())
+		delete(clients, key)
+	}
+
+	return errs
+}
+
+// getContext returns the context set by [SetContext].
+func getContext() context.Context {
+	rootCtxMu.RLock()
+	defer rootCtxMu.RUnlock()
+
+	return rootCtx
+}
+
+// templateKwArgsType declares the keyword arguments shared by the template
+// methods of [Plugin].
+var templateKwArgsType = map[string]string{
+	argChart:                plugins.TypeStr,
+	argTargetRevision:       plugins.TypeStr,
+	argRepoURL:              plugins.TypeStr,
+	argReleaseName:          plugins.TypeStr,
+	argNamespace:            plugins.TypeStr,
+	argSkipCRDs:             plugins.TypeBool,
+	argSkipSchemaValidation: plugins.TypeBool,
+	argSkipHooks:            plugins.TypeBool,
+	argPassCredentials:      plugins.TypeBool,
+	argRepositories:         "[any]",
+	argValues:               "{str:any}",
+	argKubeVersion:          plugins.TypeStr,
+	argAPIVersions:          "[str]",
+	argLookups:              "[{str:any}]",
+	argSort:                 plugins.TypeStr,
+	argTimeout:              plugins.TypeStr,
+	argCommonLabels:         "{str:str}",
+	argCommonAnnotations:    "{str:str}",
+	argForceNamespace:       plugins.TypeBool,
+	argClusterScopedKinds:   "[str]",
+	argCreateNamespace:      plugins.TypeBool,
+	argArgoCDAppName:        plugins.TypeStr,
+	argSet:                  "[str]",
+	argSetString:            "[str]",
+	argSetFile:              "[str]",
+	argValueFiles:           "[str]",
+}
+
+// Plugin is the KCL plugin that exposes Helm functionality.
 var Plugin = plugin.Plugin{
 	Name: "helm",
 	MethodMap: map[string]plugin.MethodSpec{
 		"template": {
+			Type: &plugin.MethodType{
+				KwArgsType: templateKwArgsType,
+				ResultType: "[{str:any}]",
+			},
+			Body: templateBody,
+		},
+		"template_release": {
+			Type: &plugin.MethodType{
+				KwArgsType: templateKwArgsType,
+				ResultType: "{str:any}",
+			},
+			Body: templateReleaseBody,
+		},
+		"template_all": {
+			Type: &plugin.MethodType{
+				KwArgsType: map[string]string{
+					argCharts:      "[{str:any}]",
+					argConcurrency: plugins.TypeInt,
+					argTimeout:     plugins.TypeStr,
+				},
+				ResultType: "{str:[{str:any}]}",
+			},
+			Body: templateAllBody,
+		},
+		"chart_info": {
+			Type: &plugin.MethodType{
+				KwArgsType: map[string]string{
+					argChart:          plugins.TypeStr,
+					argTargetRevision: plugins.TypeStr,
+					argRepoURL:        plugins.TypeStr,
+					argRepositories:   "[any]",
+					argTimeout:        plugins.TypeStr,
+				},
+				ResultType: "{str:any}",
+			},
+			Body: chartInfoBody,
+		},
+		"values": {
 			Type: &plugin.MethodType{
 				KwArgsType: map[string]string{
 					argChart:                plugins.TypeStr,
 					argTargetRevision:       plugins.TypeStr,
 					argRepoURL:              plugins.TypeStr,
-					argReleaseName:          plugins.TypeStr,
-					argNamespace:            plugins.TypeStr,
-					argSkipCRDs:             plugins.TypeBool,
 					argSkipSchemaValidation: plugins.TypeBool,
-					argSkipHooks:            plugins.TypeBool,
-					argPassCredentials:      plugins.TypeBool,
 					argRepositories:         "[any]",
 					argValues:               "{str:any}",
+					argSet:                  "[str]",
+					argSetString:            "[str]",
+					argSetFile:              "[str]",
+					argValueFiles:           "[str]",
+					argTimeout:              plugins.TypeStr,
 				},
-				ResultType: "[{str:any}]",
-			},
-			Body: func(args *plugin.MethodArgs) (*plugin.MethodResult, error) {
-				logger := slog.With(
-					slog.String("plugin", "helm"),
-					slog.String("method", "template"),
-				)
-				logger.Debug("invoking kcl plugin")
-
-				safeArgs := plugins.SafeMethodArgs{Args: args}
-
-				var validationErr error
-
-				if !safeArgs.Exists(argChart) {
-					validationErr = errors.Join(validationErr, fmt.Errorf("missing required argument: %s", argChart))
-				}
-
-				if !safeArgs.Exists(argRepoURL) {
-					validationErr = errors.Join(validationErr, fmt.Errorf("missing required argument: %s", argRepoURL))
-				}
-
-				if validationErr != nil {
-					return nil, validationErr
-				}
-
-				chartName := args.StrKwArg(argChart)
-				logger = logger.With(
-					slog.String(argChart, chartName),
-				)
-
-				repoURL := args.StrKwArg(argRepoURL)
-				targetRevision := safeArgs.StrKwArg(argTargetRevision, "")
-				repos := safeArgs.ListKwArg(argRepositories, []any{})
-				releaseName := safeArgs.StrKwArg(argReleaseName, chartName)
-				skipCRDs := safeArgs.BoolKwArg(argSkipCRDs, false)
-				skipSchemaValidation := safeArgs.BoolKwArg(argSkipSchemaValidation, true)
-				skipHooks := safeArgs.BoolKwArg(argSkipHooks, false)
-				passCredentials := safeArgs.BoolKwArg(argPassCredentials, false)
-				values := safeArgs.MapKwArg(argValues, map[string]any{})
-
-				// https://argo-cd.readthedocs.io/en/stable/user-guide/build-environment/
-				// https://github.com/argoproj/argo-cd/pull/15186
-				project := os.Getenv("ARGOCD_APP_PROJECT_NAME")
-				namespace := safeArgs.StrKwArg(argNamespace, os.Getenv("ARGOCD_APP_NAMESPACE"))
-				kubeVersion := os.Getenv("KUBE_VERSION")
-				kubeAPIVersions := os.Getenv("KUBE_API_VERSIONS")
-
-				timeoutStr, ok := os.LookupEnv("ARGOCD_EXEC_TIMEOUT")
-				if !ok {
-					timeoutStr = "60s"
-				}
-
-				timeout, err := time.ParseDuration(timeoutStr)
-				if err != nil {
-					return nil, fmt.Errorf("parse timeout: %w", err)
-				}
-
-				cwd := os.Getenv("ARGOCD_APP_SOURCE_PATH")
-				if cwd == "" {
-					cwd = "."
-				}
-
-				repoRoot, err := paths.FindRepoRoot(cwd)
-				if err != nil {
-					return nil, fmt.Errorf("find repository root: %w", err)
-				}
-
-				pkgPath, err := paths.FindTopPkgRoot(repoRoot, cwd)
-				if err != nil {
-					return nil, fmt.Errorf("find package root: %w", err)
-				}
-
-				logger.Debug("set arguments",
-					slog.String(argRepoURL, repoURL),
-					slog.String(argTargetRevision, targetRevision),
-					slog.String(argNamespace, namespace),
-					slog.String(argReleaseName, releaseName),
-					slog.Bool(argSkipCRDs, skipCRDs),
-					slog.Bool(argSkipSchemaValidation, skipSchemaValidation),
-					slog.Bool(argSkipHooks, skipHooks),
-					slog.Bool(argPassCredentials, passCredentials),
-					slog.String("project", project),
-					slog.String("kube_version", kubeVersion),
-					slog.String("kube_api_versions", kubeAPIVersions),
-					slog.String("cwd", cwd),
-					slog.String("pkg_path", pkgPath),
-					slog.String("repo_root", repoRoot),
-					slog.String("timeout", timeout.String()),
-				)
-
-				repoMgr := helmrepo.NewManager(helmrepo.WithAllowedPaths(pkgPath, repoRoot))
-				for _, repo := range repos {
-					var pcr kclhelm.ChartRepo
-
-					repoMap, ok := repo.(map[string]any)
-					if !ok {
-						return nil, fmt.Errorf("invalid repository: %#v", repo)
-					}
-
-					err := pcr.FromMap(repoMap)
-					if err != nil {
-						return nil, fmt.Errorf("invalid repository: %w", err)
-					}
-
-					hr, err := pcr.GetHelmRepo()
-					if err != nil {
-						return nil, fmt.Errorf("add helm repository: %w", err)
-					}
-
-					err = repoMgr.Add(hr)
-					if err != nil {
-						return nil, fmt.Errorf("add helm repository: %w", err)
-					}
-				}
-
-				tempPaths := paths.NewStaticTempPaths(
-					filepath.Join(os.TempDir(), "charts"),
-					paths.NewBase64PathEncoder(),
-				)
-				helmClient, err := helm.NewClient(tempPaths, project)
-				if err != nil {
-					return nil, fmt.Errorf("create helm client: %w", err)
-				}
-
-				helmChart := helm.NewChart(helmClient, repoMgr, &helm.TemplateOpts{
-					ChartName:            chartName,
-					TargetRevision:       targetRevision,
-					RepoURL:              repoURL,
-					ReleaseName:          releaseName,
-					Namespace:            namespace,
-					SkipCRDs:             skipCRDs,
-					SkipSchemaValidation: skipSchemaValidation,
-					SkipHooks:            skipHooks,
-					PassCredentials:      passCredentials,
-					ValuesObject:         values,
-					KubeVersion:          kubeVersion,
-					APIVersions:          strings.Split(kubeAPIVersions, ","),
-					Timeout:              timeout,
-				})
-
-				logger.Info("execute helm template")
-
-				objs, err := helmChart.Template(context.Background())
-				if err != nil {
-					return nil, fmt.Errorf("template %q: %w", chartName, err)
-				}
-
-				logger.Info("helm template complete")
-
-				logger.Debug("returning results")
-
-				return &plugin.MethodResult{V: kube.ObjectsToMaps(objs)}, nil
+				ResultType: "{str:any}",
 			},
+			Body: valuesBody,
 		},
 	},
 }
+
+// validateChartArgs checks that the arguments required to locate a chart
+// are present.
+func validateChartArgs(safeArgs plugins.SafeMethodArgs) error {
+	var validationErr error
+
+	if !safeArgs.Exists(argChart) {
+		validationErr = errors.Join(validationErr, fmt.Errorf("missing required argument: %s", argChart))
+	}
+
+	if !safeArgs.Exists(argRepoURL) {
+		validationErr = errors.Join(validationErr, fmt.Errorf("missing required argument: %s", argRepoURL))
+	}
+
+	return validationErr
+}
+
+// toObjects converts a list of KCL dicts to a list of [kube.Object].
+func toObjects(list []any) ([]kube.Object, error) {
+	objs := make([]kube.Object, 0, len(list))
+	for i, item := range list {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected object at index %d, got %T", i, item)
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// toStringMap converts a KCL dict of strings to a map[string]string.
+func toStringMap(m map[string]any) (map[string]string, error) {
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string value for key %q, got %T", k, v)
+		}
+
+		result[k] = str
+	}
+
+	return result, nil
+}
+
+// setValues reads the Helm-style value overrides from safeArgs.
+func setValues(safeArgs plugins.SafeMethodArgs) (*helm.SetValues, error) {
+	set, err := safeArgs.ListStrKwArg(argSet, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", argSet, err)
+	}
+
+	setString, err := safeArgs.ListStrKwArg(argSetString, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", argSetString, err)
+	}
+
+	setFile, err := safeArgs.ListStrKwArg(argSetFile, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", argSetFile, err)
+	}
+
+	return &helm.SetValues{
+		Set:       set,
+		SetString: setString,
+		SetFile:   setFile,
+	}, nil
+}
+
+// environment holds the settings that the plugin reads from its execution
+// environment rather than from method arguments.
+type environment struct {
+	project  string
+	proxy    string
+	noProxy  string
+	cwd      string
+	repoRoot string
+	pkgPath  string
+	timeout  time.Duration
+}
+
+// getEnvironment reads the [environment] from the Argo CD build environment
+// variables, and locates the repository and KCL package roots. If timeout is
+// set, it is used instead of the `ARGOCD_EXEC_TIMEOUT` environment variable.
+// Chart downloads are routed through the proxy set by `KCLIPPER_HELM_PROXY`,
+// except for hosts matching `KCLIPPER_HELM_NO_PROXY`.
+//
+// https://argo-cd.readthedocs.io/en/stable/user-guide/build-environment/
+// https://github.com/argoproj/argo-cd/pull/15186
+func getEnvironment(timeout string) (*environment, error) {
+	timeoutStr := timeout
+	if timeoutStr == "" {
+		var ok bool
+
+		timeoutStr, ok = os.LookupEnv("ARGOCD_EXEC_TIMEOUT")
+		if !ok {
+			timeoutStr = "60s"
+		}
+	}
+
+	execTimeout, err := time.ParseDuration(timeoutStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse timeout: %w", err)
+	}
+
+	cwd := os.Getenv("ARGOCD_APP_SOURCE_PATH")
+	if cwd == "" {
+		cwd = "."
+	}
+
+	repoRoot, err := paths.FindRepoRoot(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("find repository root: %w", err)
+	}
+
+	pkgPath, err := paths.FindTopPkgRoot(repoRoot, cwd)
+	if err != nil {
+		return nil, fmt.Errorf("find package root: %w", err)
+	}
+
+	return &environment{
+		project:  os.Getenv("ARGOCD_APP_PROJECT_NAME"),
+		proxy:    os.Getenv("KCLIPPER_HELM_PROXY"),
+		noProxy:  os.Getenv("KCLIPPER_HELM_NO_PROXY"),
+		cwd:      cwd,
+		repoRoot: repoRoot,
+		pkgPath:  pkgPath,
+		timeout:  execTimeout,
+	}, nil
+}
+
+// newRepoManager creates a [helmrepo.Manager] containing the given KCL
+// repository definitions.
+func (e *environment) newRepoManager(repos []any) (*helmrepo.Manager, error) {
+	repoMgr := helmrepo.NewManager(helmrepo.WithAllowedPaths(e.pkgPath, e.repoRoot))
+	for _, repo := range repos {
+		var pcr kclhelm.ChartRepo
+
+		repoMap, ok := repo.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid repository: %#v", repo)
+		}
+
+		err := pcr.FromMap(repoMap)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository: %w", err)
+		}
+
+		hr, err := pcr.GetHelmRepo(e.pkgPath, e.repoRoot)
+		if err != nil {
+			return nil, fmt.Errorf("add helm repository: %w", err)
+		}
+
+		err = repoMgr.Add(hr)
+		if err != nil {
+			return nil, fmt.Errorf("add helm repository: %w", err)
+		}
+	}
+
+	return repoMgr, nil
+}
+
+// readValueFiles reads the values files at the given paths with client. Paths
+// are relative to the environment's package path, see
+// [helm.Client.ReadValuesFile]. Files are read within the environment's
+// timeout.
+func (e *environment) readValueFiles(client *helm.Client, files []string) ([]*helm.ValuesFile, error) {
+	ctx, cancel := getContext(), func() {}
+	if e.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+	}
+
+	defer cancel()
+
+	valueFiles := make([]*helm.ValuesFile, 0, len(files))
+	for _, f := range files {
+		vf, err := client.ReadValuesFile(ctx, f, e.pkgPath, e.repoRoot)
+		if errors.Is(err, os.ErrNotExist) {
+			vf, err = readWorkingDirValuesFile(ctx, client, f, e.repoRoot, err)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", argValueFiles, err)
+		}
+
+		valueFiles = append(valueFiles, vf)
+	}
+
+	return valueFiles, nil
+}
+
+// readWorkingDirValuesFile reads the values file at the relative path file
+// from the working directory, which is where values files were read from
+// before they were resolved relative to the KCL package. It returns pkgErr,
+// the error from reading the file relative to the package, if file is not a
+// relative path, or if it does not exist in the working directory either.
+func readWorkingDirValuesFile(
+	ctx context.Context,
+	client *helm.Client,
+	file, repoRoot string,
+	pkgErr error,
+) (*helm.ValuesFile, error) {
+	if strings.HasPrefix(file, "/") || strings.Contains(file, "://") {
+		return nil, pkgErr
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, pkgErr
+	}
+
+	vf, err := client.ReadValuesFile(ctx, file, wd, repoRoot)
+	if err != nil {
+		return nil, pkgErr
+	}
+
+	slog.WarnContext(ctx, "values file was resolved relative to the working directory, "+
+		"which is deprecated; make the path relative to the KCL package instead",
+		slog.String("file", file),
+		slog.String("working_dir", wd),
+	)
+
+	return vf, nil
+}
+
+// clientKey identifies a shared [helm.Client]. The package path is included
+// because the client's chart lock is read relative to it.
+type clientKey struct {
+	project string
+	proxy   string
+	noProxy string
+	pkgPath string
+}
+
+// sharedClient returns the [helm.Client] for the environment's project, proxy
+// settings and package. Clients are created on first use and shared by all later
+// calls, so that their Helm home, OCI registry client and registry auth cache
+// are reused. See [Close].
+func (e *environment) sharedClient() (*helm.Client, error) {
+	key := clientKey{project: e.project, proxy: e.proxy, noProxy: e.noProxy, pkgPath: e.pkgPath}
+
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	if c, ok := clients[key]; ok {
+		return c, nil
+	}
+
+	c, err := e.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	clients[key] = c
+
+	return c, nil
+}
+
+// newClient creates a [helm.Client] backed by the shared chart cache. The
+// `KCLIPPER_HELM_VERIFY` and `KCLIPPER_HELM_KEYRING` environment variables set
+// the default chart provenance verification for all repositories, and chart
+// downloads are routed through the environment's proxy, if any. Pulled charts
+// are verified against the lock returned by [environment.readLock], the cache
+// is bounded by [cacheLimitsFromEnv], repository indexes are cached as
+// configured by [indexCacheFromEnv], stored credentials are read as
+// configured by [credentialStoresFromEnv], and values files are fetched as
+// configured by [valuesFileURLsFromEnv].
+func (e *environment) newClient() (*helm.Client, error) {
+	tempPaths := paths.NewStaticTempPaths(
+		filepath.Join(os.TempDir(), "charts"),
+		paths.NewBase64PathEncoder(),
+	)
+
+	opts := []helm.ClientOption{
+		helm.WithVerify(
+			helmrepo.VerifyMode(os.Getenv("KCLIPPER_HELM_VERIFY")),
+			os.Getenv("KCLIPPER_HELM_KEYRING"),
+		),
+		helm.WithProxy(e.proxy, e.noProxy),
+	}
+
+	maxCacheSize, maxCacheAge, err := cacheLimitsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	indexCache, err := indexCacheFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	credentialStores, err := credentialStoresFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	valuesFileURLs, err := valuesFileURLsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append(opts,
+		helm.WithCacheLimits(maxCacheSize, maxCacheAge),
+		indexCache,
+		credentialStores,
+		valuesFileURLs,
+	)
+
+	lock, err := e.readLock()
+	if err != nil {
+		return nil, err
+	}
+
+	if lock != nil {
+		opts = append(opts, helm.WithLock(lock))
+	}
+
+	helmClient, err := helm.NewClient(tempPaths, e.project, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create helm client: %w", err)
+	}
+
+	return helmClient, nil
+}
+
+// cacheLimitsFromEnv returns the maximum size and age of the chart and render
+// caches, set by the `KCLIPPER_CHART_CACHE_MAX_SIZE` (a quantity, e.g. `5Gi`)
+// and `KCLIPPER_CHART_CACHE_MAX_AGE` (a duration, e.g. `168h`) environment
+// variables. Unset variables leave the respective limit disabled.
+func cacheLimitsFromEnv() (int64, time.Duration, error) {
+	var (
+		maxSize int64
+		maxAge  time.Duration
+	)
+
+	if v := os.Getenv("KCLIPPER_CHART_CACHE_MAX_SIZE"); v != "" {
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parse KCLIPPER_CHART_CACHE_MAX_SIZE: %w", err)
+		}
+
+		maxSize = q.Value()
+	}
+
+	if v := os.Getenv("KCLIPPER_CHART_CACHE_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parse KCLIPPER_CHART_CACHE_MAX_AGE: %w", err)
+		}
+
+		maxAge = d
+	}
+
+	return maxSize, maxAge, nil
+}
+
+// indexCacheFromEnv returns a [helm.ClientOption] that sets the repository
+// index cache, which is stored next to the chart cache. Cached indexes are
+// revalidated after the duration set by `KCLIPPER_HELM_INDEX_TTL`.
+func indexCacheFromEnv() (helm.ClientOption, error) {
+	ttl := helm.DefaultIndexCacheTTL
+
+	if v := os.Getenv("KCLIPPER_HELM_INDEX_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse KCLIPPER_HELM_INDEX_TTL: %w", err)
+		}
+
+		ttl = d
+	}
+
+	return helm.WithIndexCache(helm.NewIndexCache(filepath.Join(os.TempDir(), "indexes"), ttl)), nil
+}
+
+// credentialStoresFromEnv returns a [helm.ClientOption] that enables or
+// disables reading credentials from Docker's and Helm's config files, as set
+// by the `KCLIPPER_HELM_CREDENTIAL_STORES` environment variable. They are
+// enabled by default, and should be disabled when charts are rendered for
+// multiple tenants.
+func credentialStoresFromEnv() (helm.ClientOption, error) {
+	enabled := true
+
+	if v := os.Getenv("KCLIPPER_HELM_CREDENTIAL_STORES"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse KCLIPPER_HELM_CREDENTIAL_STORES: %w", err)
+		}
+
+		enabled = b
+	}
+
+	return helm.WithCredentialStores(enabled), nil
+}
+
+// valuesFileURLsFromEnv returns a [helm.ClientOption] that allows values files
+// to be fetched from URLs with the comma-separated hosts in the
+// `KCLIPPER_HELM_VALUES_FILE_HOSTS` environment variable, up to the size set
+// by `KCLIPPER_HELM_VALUES_FILE_MAX_SIZE` (a quantity, e.g. `1Mi`). No URLs
+// are fetched if the hosts are unset.
+func valuesFileURLsFromEnv() (helm.ClientOption, error) {
+	var (
+		hosts   []string
+		maxSize int64
+	)
+
+	for host := range strings.SplitSeq(os.Getenv("KCLIPPER_HELM_VALUES_FILE_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	if v := os.Getenv("KCLIPPER_HELM_VALUES_FILE_MAX_SIZE"); v != "" {
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse KCLIPPER_HELM_VALUES_FILE_MAX_SIZE: %w", err)
+		}
+
+		maxSize = q.Value()
+	}
+
+	return helm.WithValuesFileURLs(hosts, maxSize), nil
+}
+
+// newRenderCache returns the [helm.RenderCache] enabled by the
+// `KCLIPPER_RENDER_CACHE` environment variable, which is stored next to the
+// chart cache and bounded by the same limits, see [cacheLimitsFromEnv].
+// Returns nil if it is not enabled.
+func newRenderCache() (*helm.RenderCache, error) {
+	v := os.Getenv("KCLIPPER_RENDER_CACHE")
+	if v == "" {
+		return nil, nil //nolint:nilnil // The render cache is disabled.
+	}
+
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("parse KCLIPPER_RENDER_CACHE: %w", err)
+	}
+
+	if !enabled {
+		return nil, nil //nolint:nilnil // The render cache is disabled.
+	}
+
+	maxSize, maxAge, err := cacheLimitsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := helm.NewRenderCache(
+		filepath.Join(os.TempDir(), "renders"),
+		helm.WithRenderCacheLimits(maxSize, maxAge),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create render cache: %w", err)
+	}
+
+	return rc, nil
+}
+
+// readLock reads the charts.lock file at the path set by the
+// `KCLIPPER_CHARTS_LOCK` environment variable, relative to the package root.
+// If it is unset, `charts/charts.lock` is read if it exists. Returns nil if
+// there is no lock to read.
+func (e *environment) readLock() (*chartlock.Lock, error) {
+	lockPath, ok := os.LookupEnv("KCLIPPER_CHARTS_LOCK")
+	if !ok {
+		lockPath = filepath.Join("charts", chartlock.FileName)
+	}
+
+	if lockPath == "" {
+		return nil, nil //nolint:nilnil // Locking is disabled.
+	}
+
+	resolved, err := paths.ResolveFilePathOrURL(e.pkgPath, e.repoRoot, lockPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("resolve chart lock path: %w", err)
+	}
+
+	lock, err := chartlock.ReadFile(resolved.String())
+	if !ok && errors.Is(err, os.ErrNotExist) {
+		return nil, nil //nolint:nilnil // There is no default lock.
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read chart lock: %w", err)
+	}
+
+	return lock, nil
+}
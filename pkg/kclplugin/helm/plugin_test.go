@@ -26,6 +26,10 @@ func init() {
 func TestPluginHelmTemplate(t *testing.T) {
 	helmplugin.Register()
 
+	t.Cleanup(func() {
+		assert.NoError(t, helmplugin.Close())
+	})
+
 	workDir := testDataDir
 	t.Chdir(workDir)
 
@@ -41,6 +45,22 @@ func TestPluginHelmTemplate(t *testing.T) {
 			kclFile:     "input/local.k",
 			resultsFile: "output/local.json",
 		},
+		"ChartInfo": {
+			kclFile:     "input/chart_info.k",
+			resultsFile: "output/chart_info.json",
+		},
+		"Values": {
+			kclFile:     "input/values.k",
+			resultsFile: "output/values.json",
+		},
+		"TemplateRelease": {
+			kclFile:     "input/template_release.k",
+			resultsFile: "output/template_release.json",
+		},
+		"TemplateAll": {
+			kclFile:     "input/template_all.k",
+			resultsFile: "output/template_all.json",
+		},
 	}
 	for name, tc := range tcs {
 		t.Run(name, func(t *testing.T) {
@@ -64,3 +84,34 @@ func TestPluginHelmTemplate(t *testing.T) {
 		})
 	}
 }
+
+// The kube_version and api_versions kwargs take precedence over the
+// KUBE_VERSION and KUBE_API_VERSIONS environment variables, which are used
+// when they are not set.
+//
+//nolint:paralleltest // Due to t.Chdir and t.Setenv.
+func TestPluginHelmTemplateKubeVersion(t *testing.T) {
+	helmplugin.Register()
+
+	t.Cleanup(func() {
+		assert.NoError(t, helmplugin.Close())
+	})
+
+	t.Chdir(testDataDir)
+	t.Setenv("KUBE_VERSION", "1.20.0")
+	t.Setenv("KUBE_API_VERSIONS", "env.example.com/v1")
+
+	want, err := os.ReadFile(filepath.Join(testDataDir, "output/kube_version.json"))
+	require.NoError(t, err)
+
+	client := native.NewNativeServiceClient()
+	result, err := client.ExecProgram(&gpyrpc.ExecProgramArgs{
+		KFilenameList: []string{"input/kube_version.k"},
+		WorkDir:       testDataDir,
+		Args:          []*gpyrpc.Argument{},
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.GetErrMessage(), result.GetLogMessage())
+
+	assert.JSONEq(t, string(want), result.GetJsonResult())
+}
@@ -0,0 +1,237 @@
+package helm
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"kcl-lang.io/kcl-go/pkg/plugin"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/kclplugin/plugins"
+	"github.com/macropower/kclipper/pkg/kube"
+)
+
+// templateBody implements the "template" method of [Plugin].
+func templateBody(args *plugin.MethodArgs) (*plugin.MethodResult, error) {
+	logger := slog.With(
+		slog.String("plugin", "helm"),
+		slog.String("method", "template"),
+	)
+	logger.Debug("invoking kcl plugin")
+
+	helmChart, logger, err := newTemplateChart(args, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("execute helm template")
+
+	objs, err := helmChart.Template(getContext())
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", helmChart.TemplateOpts.ChartName, err)
+	}
+
+	logger.Info("helm template complete")
+
+	logger.Debug("returning results")
+
+	return &plugin.MethodResult{V: kube.ObjectsToMaps(objs)}, nil
+}
+
+// templateReleaseBody implements the "template_release" method of [Plugin].
+func templateReleaseBody(args *plugin.MethodArgs) (*plugin.MethodResult, error) {
+	logger := slog.With(
+		slog.String("plugin", "helm"),
+		slog.String("method", "template_release"),
+	)
+	logger.Debug("invoking kcl plugin")
+
+	helmChart, logger, err := newTemplateChart(args, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("execute helm template")
+
+	rel, err := helmChart.TemplateRelease(getContext())
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", helmChart.TemplateOpts.ChartName, err)
+	}
+
+	logger.Info("helm template complete")
+
+	logger.Debug("returning results")
+
+	return &plugin.MethodResult{V: rel.ToMap()}, nil
+}
+
+// newTemplateChart creates a [helm.Chart] from the arguments shared by the
+// template methods of [Plugin]. The returned logger includes the chart name.
+func newTemplateChart(args *plugin.MethodArgs, logger *slog.Logger) (*helm.Chart, *slog.Logger, error) {
+	safeArgs := plugins.SafeMethodArgs{Args: args}
+
+	err := validateChartArgs(safeArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chartName := args.StrKwArg(argChart)
+	logger = logger.With(
+		slog.String(argChart, chartName),
+	)
+
+	repoURL := args.StrKwArg(argRepoURL)
+	targetRevision := safeArgs.StrKwArg(argTargetRevision, "")
+	repos := safeArgs.ListKwArg(argRepositories, []any{})
+	releaseName := safeArgs.StrKwArg(argReleaseName, chartName)
+	skipCRDs := safeArgs.BoolKwArg(argSkipCRDs, false)
+	skipSchemaValidation := safeArgs.BoolKwArg(argSkipSchemaValidation, true)
+	skipHooks := safeArgs.BoolKwArg(argSkipHooks, false)
+	passCredentials := safeArgs.BoolKwArg(argPassCredentials, false)
+	values := safeArgs.MapKwArg(argValues, map[string]any{})
+
+	namespace := safeArgs.StrKwArg(argNamespace, os.Getenv("ARGOCD_APP_NAMESPACE"))
+	kubeVersion := safeArgs.StrKwArg(argKubeVersion, "")
+	if kubeVersion == "" {
+		kubeVersion = os.Getenv("KUBE_VERSION")
+	}
+
+	apiVersions, err := safeArgs.ListStrKwArg(argAPIVersions, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid argument: %w", err)
+	}
+
+	if len(apiVersions) == 0 {
+		apiVersions = strings.Split(os.Getenv("KUBE_API_VERSIONS"), ",")
+	}
+
+	lookups, err := toObjects(safeArgs.ListKwArg(argLookups, []any{}))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", argLookups, err)
+	}
+
+	sortOrder, err := helm.GetSortOrder(safeArgs.StrKwArg(argSort, ""))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", argSort, err)
+	}
+
+	commonLabels, err := toStringMap(safeArgs.MapKwArg(argCommonLabels, nil))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", argCommonLabels, err)
+	}
+
+	commonAnnotations, err := toStringMap(safeArgs.MapKwArg(argCommonAnnotations, nil))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", argCommonAnnotations, err)
+	}
+
+	forceNamespace := safeArgs.BoolKwArg(argForceNamespace, false)
+
+	clusterScopedKinds, err := safeArgs.ListStrKwArg(argClusterScopedKinds, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", argClusterScopedKinds, err)
+	}
+
+	createNamespace := safeArgs.BoolKwArg(argCreateNamespace, false)
+	argoCDAppName := safeArgs.StrKwArg(argArgoCDAppName, "")
+
+	overrides, err := setValues(safeArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	valueFilePaths, err := safeArgs.ListStrKwArg(argValueFiles, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", argValueFiles, err)
+	}
+
+	env, err := getEnvironment(safeArgs.StrKwArg(argTimeout, ""))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = overrides.ReadFiles(env.pkgPath, env.repoRoot)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read value override files: %w", err)
+	}
+
+	logger.Debug("set arguments",
+		slog.String(argRepoURL, repoURL),
+		slog.String(argTargetRevision, targetRevision),
+		slog.String(argNamespace, namespace),
+		slog.String(argReleaseName, releaseName),
+		slog.Bool(argSkipCRDs, skipCRDs),
+		slog.Bool(argSkipSchemaValidation, skipSchemaValidation),
+		slog.Bool(argSkipHooks, skipHooks),
+		slog.Bool(argPassCredentials, passCredentials),
+		slog.String("project", env.project),
+		slog.String(argKubeVersion, kubeVersion),
+		slog.String(argAPIVersions, strings.Join(apiVersions, ",")),
+		slog.Int(argLookups, len(lookups)),
+		slog.String(argSort, string(sortOrder)),
+		slog.Int(argCommonLabels, len(commonLabels)),
+		slog.Int(argCommonAnnotations, len(commonAnnotations)),
+		slog.Bool(argForceNamespace, forceNamespace),
+		slog.String(argClusterScopedKinds, strings.Join(clusterScopedKinds, ",")),
+		slog.Bool(argCreateNamespace, createNamespace),
+		slog.String(argArgoCDAppName, argoCDAppName),
+		slog.Int(argValueFiles, len(valueFilePaths)),
+		slog.Int(argSet, len(overrides.Set)),
+		slog.Int(argSetString, len(overrides.SetString)),
+		slog.Int(argSetFile, len(overrides.SetFile)),
+		slog.String("cwd", env.cwd),
+		slog.String("pkg_path", env.pkgPath),
+		slog.String("repo_root", env.repoRoot),
+		slog.String("timeout", env.timeout.String()),
+	)
+
+	repoMgr, err := env.newRepoManager(repos)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	helmClient, err := env.sharedClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	valueFiles, err := env.readValueFiles(helmClient, valueFilePaths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	helmChart := helm.NewChart(helmClient, repoMgr, &helm.TemplateOpts{
+		ChartName:            chartName,
+		TargetRevision:       targetRevision,
+		RepoURL:              repoURL,
+		ReleaseName:          releaseName,
+		Namespace:            namespace,
+		SkipCRDs:             skipCRDs,
+		SkipSchemaValidation: skipSchemaValidation,
+		SkipHooks:            skipHooks,
+		PassCredentials:      passCredentials,
+		ValueFiles:           valueFiles,
+		ValuesObject:         values,
+		SetValues:            overrides,
+		KubeVersion:          kubeVersion,
+		APIVersions:          apiVersions,
+		Lookups:              lookups,
+		Sort:                 sortOrder,
+		Timeout:              env.timeout,
+		CommonLabels:         commonLabels,
+		CommonAnnotations:    commonAnnotations,
+		ForceNamespace:       forceNamespace,
+		ClusterScopedKinds:   clusterScopedKinds,
+		CreateNamespace:      createNamespace,
+		ArgoCDAppName:        argoCDAppName,
+	})
+
+	helmChart.RenderCache, err = newRenderCache()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return helmChart, logger, nil
+}
@@ -0,0 +1,174 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"kcl-lang.io/kcl-go/pkg/plugin"
+
+	"github.com/macropower/kclipper/pkg/kclplugin/plugins"
+	"github.com/macropower/kclipper/pkg/kube"
+)
+
+// templateAllBody implements the "template_all" method of [Plugin]. Each
+// chart spec accepts the keyword arguments of the "template" method, and an
+// optional key that identifies the chart in the results. The key defaults to
+// the release name, or to the chart name if no release name is set.
+func templateAllBody(args *plugin.MethodArgs) (*plugin.MethodResult, error) {
+	logger := slog.With(
+		slog.String("plugin", "helm"),
+		slog.String("method", "template_all"),
+	)
+	logger.Debug("invoking kcl plugin")
+
+	safeArgs := plugins.SafeMethodArgs{Args: args}
+
+	specs, err := chartSpecs(safeArgs.ListKwArg(argCharts, []any{}), safeArgs.StrKwArg(argTimeout, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	workerCount := safeArgs.IntKwArg(argConcurrency, 0)
+	if workerCount <= 0 {
+		workerCount = int64(runtime.GOMAXPROCS(0))
+	}
+
+	logger.Info("execute helm template",
+		slog.Int("charts", len(specs)),
+		slog.Int64(argConcurrency, workerCount),
+	)
+
+	ctx := getContext()
+
+	var workers errgroup.Group
+
+	workers.SetLimit(int(workerCount))
+
+	// Errors are indexed by spec, so that they are reported in order.
+	errs := make([]error, len(specs))
+
+	var (
+		results   = make(map[string]any, len(specs))
+		resultsMu sync.Mutex
+	)
+
+	for i, spec := range specs {
+		// Stop starting workers once the call is cancelled. Workers that
+		// have already started observe the same context.
+		if ctx.Err() != nil {
+			break
+		}
+
+		workers.Go(func() error {
+			objs, err := templateSpec(spec, logger.With(slog.String(argKey, spec.key)))
+			if err != nil {
+				errs[i] = fmt.Errorf("chart %q: %w", spec.key, err)
+
+				return nil
+			}
+
+			resultsMu.Lock()
+			defer resultsMu.Unlock()
+
+			results[spec.key] = kube.ObjectsToMaps(objs)
+
+			return nil
+		})
+	}
+
+	// Always wait for every started worker, so that none of them outlive
+	// the call.
+	err = workers.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return nil, fmt.Errorf("template charts: %w", err)
+	}
+
+	err = errors.Join(errs...)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("helm template complete")
+
+	logger.Debug("returning results")
+
+	return &plugin.MethodResult{V: results}, nil
+}
+
+// chartSpec is a chart passed to the "template_all" method of [Plugin].
+type chartSpec struct {
+	args *plugin.MethodArgs
+	key  string
+}
+
+// chartSpecs converts the chart specs passed to the "template_all" method of
+// [Plugin] to [chartSpec]s. Specs without a timeout use defaultTimeout.
+func chartSpecs(list []any, defaultTimeout string) ([]chartSpec, error) {
+	specs := make([]chartSpec, 0, len(list))
+	keys := make(map[string]bool, len(list))
+
+	for i, item := range list {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: expected object at index %d, got %T", argCharts, i, item)
+		}
+
+		// Unset optional attributes are passed as None, which the template
+		// methods treat as missing arguments.
+		kwargs := make(map[string]any, len(obj)+1)
+		for k, v := range obj {
+			if v != nil {
+				kwargs[k] = v
+			}
+		}
+
+		if _, ok := kwargs[argTimeout]; !ok && defaultTimeout != "" {
+			kwargs[argTimeout] = defaultTimeout
+		}
+
+		safeArgs := plugins.SafeMethodArgs{Args: &plugin.MethodArgs{KwArgs: kwargs}}
+
+		key := safeArgs.StrKwArg(argKey, safeArgs.StrKwArg(argReleaseName, safeArgs.StrKwArg(argChart, "")))
+		if key == "" {
+			return nil, fmt.Errorf("invalid %s: missing %s at index %d", argCharts, argChart, i)
+		}
+
+		if keys[key] {
+			return nil, fmt.Errorf("invalid %s: duplicate key %q at index %d", argCharts, key, i)
+		}
+
+		keys[key] = true
+
+		delete(kwargs, argKey)
+
+		specs = append(specs, chartSpec{key: key, args: safeArgs.Args})
+	}
+
+	return specs, nil
+}
+
+// templateSpec renders the chart described by spec.
+func templateSpec(spec chartSpec, logger *slog.Logger) ([]kube.Object, error) {
+	helmChart, logger, err := newTemplateChart(spec.args, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := helmChart.Template(getContext())
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", helmChart.TemplateOpts.ChartName, err)
+	}
+
+	logger.Debug("helm template complete")
+
+	return objs, nil
+}
@@ -0,0 +1,110 @@
+package helm
+
+import (
+	"fmt"
+	"log/slog"
+
+	"kcl-lang.io/kcl-go/pkg/plugin"
+
+	"github.com/macropower/kclipper/pkg/helm"
+	"github.com/macropower/kclipper/pkg/kclplugin/plugins"
+)
+
+// valuesBody implements the "values" method of [Plugin].
+func valuesBody(args *plugin.MethodArgs) (*plugin.MethodResult, error) {
+	logger := slog.With(
+		slog.String("plugin", "helm"),
+		slog.String("method", "values"),
+	)
+	logger.Debug("invoking kcl plugin")
+
+	safeArgs := plugins.SafeMethodArgs{Args: args}
+
+	err := validateChartArgs(safeArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	chartName := args.StrKwArg(argChart)
+	logger = logger.With(
+		slog.String(argChart, chartName),
+	)
+
+	repoURL := args.StrKwArg(argRepoURL)
+	targetRevision := safeArgs.StrKwArg(argTargetRevision, "")
+	repos := safeArgs.ListKwArg(argRepositories, []any{})
+	skipSchemaValidation := safeArgs.BoolKwArg(argSkipSchemaValidation, true)
+	values := safeArgs.MapKwArg(argValues, map[string]any{})
+
+	overrides, err := setValues(safeArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	valueFilePaths, err := safeArgs.ListStrKwArg(argValueFiles, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", argValueFiles, err)
+	}
+
+	env, err := getEnvironment(safeArgs.StrKwArg(argTimeout, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	err = overrides.ReadFiles(env.pkgPath, env.repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("read value override files: %w", err)
+	}
+
+	logger.Debug("set arguments",
+		slog.String(argRepoURL, repoURL),
+		slog.String(argTargetRevision, targetRevision),
+		slog.Bool(argSkipSchemaValidation, skipSchemaValidation),
+		slog.Int(argValueFiles, len(valueFilePaths)),
+		slog.Int(argSet, len(overrides.Set)),
+		slog.Int(argSetString, len(overrides.SetString)),
+		slog.Int(argSetFile, len(overrides.SetFile)),
+		slog.String("project", env.project),
+		slog.String("cwd", env.cwd),
+		slog.String("pkg_path", env.pkgPath),
+		slog.String("repo_root", env.repoRoot),
+		slog.String("timeout", env.timeout.String()),
+	)
+
+	repoMgr, err := env.newRepoManager(repos)
+	if err != nil {
+		return nil, err
+	}
+
+	helmClient, err := env.sharedClient()
+	if err != nil {
+		return nil, err
+	}
+
+	valueFiles, err := env.readValueFiles(helmClient, valueFilePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	helmChart := helm.NewChart(helmClient, repoMgr, &helm.TemplateOpts{
+		ChartName:            chartName,
+		TargetRevision:       targetRevision,
+		RepoURL:              repoURL,
+		SkipSchemaValidation: skipSchemaValidation,
+		ValueFiles:           valueFiles,
+		ValuesObject:         values,
+		SetValues:            overrides,
+		Timeout:              env.timeout,
+	})
+
+	logger.Info("compute helm values")
+
+	result, err := helmChart.Values(getContext())
+	if err != nil {
+		return nil, fmt.Errorf("get values for %q: %w", chartName, err)
+	}
+
+	logger.Debug("returning results")
+
+	return &plugin.MethodResult{V: result}, nil
+}
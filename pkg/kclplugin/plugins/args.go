@@ -38,6 +38,15 @@ func (sma *SafeMethodArgs) BoolKwArg(name string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// IntKwArg returns the integer keyword argument with the given name, or defaultValue if it doesn't exist.
+func (sma *SafeMethodArgs) IntKwArg(name string, defaultValue int64) int64 {
+	if sma.Exists(name) {
+		return sma.Args.IntKwArg(name)
+	}
+
+	return defaultValue
+}
+
 // MapKwArg returns the map keyword argument with the given name, or defaultValue if it doesn't exist.
 func (sma *SafeMethodArgs) MapKwArg(name string, defaultValue map[string]any) map[string]any {
 	if sma.Exists(name) {
@@ -98,3 +107,29 @@ func (sma *SafeMethodArgs) ListStrArg(idx int) ([]string, error) {
 
 	return strResult, nil
 }
+
+// ListStrKwArg returns the string list keyword argument with the given name,
+// or defaultValue if it doesn't exist or is None.
+func (sma *SafeMethodArgs) ListStrKwArg(name string, defaultValue []string) ([]string, error) {
+	arg, ok := sma.Args.KwArgs[name]
+	if !ok || arg == nil {
+		return defaultValue, nil
+	}
+
+	result, ok := arg.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: expected []string argument %q, got %T",
+			kclerrors.ErrInvalidArguments, name, arg)
+	}
+
+	strResult := make([]string, len(result))
+	for i, v := range result {
+		strResult[i], ok = v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: expected string at index %d of %q, got %T",
+				kclerrors.ErrInvalidArguments, i, name, v)
+		}
+	}
+
+	return strResult, nil
+}
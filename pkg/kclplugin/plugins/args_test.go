@@ -145,6 +145,50 @@ func TestSafeMethodArgs_BoolKwArg(t *testing.T) {
 	}
 }
 
+func TestSafeMethodArgs_IntKwArg(t *testing.T) {
+	t.Parallel()
+
+	tcs := map[string]struct {
+		args         map[string]any
+		argName      string
+		defaultValue int64
+		expected     int64
+	}{
+		"key exists": {
+			args:         map[string]any{"key": int64(4)},
+			argName:      "key",
+			defaultValue: 1,
+			expected:     4,
+		},
+		"key does not exist": {
+			args:         map[string]any{"other_key": int64(4)},
+			argName:      "key",
+			defaultValue: 1,
+			expected:     1,
+		},
+		"empty args": {
+			args:         map[string]any{},
+			argName:      "key",
+			defaultValue: 1,
+			expected:     1,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			methodArgs := &plugin.MethodArgs{
+				KwArgs: tc.args,
+			}
+			safeArgs := plugins.SafeMethodArgs{Args: methodArgs}
+
+			result := safeArgs.IntKwArg(tc.argName, tc.defaultValue)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
 func TestSafeMethodArgs_MapKwArg(t *testing.T) {
 	t.Parallel()
 
@@ -350,3 +394,67 @@ func TestSafeMethodArgs_ListStrArg(t *testing.T) {
 		})
 	}
 }
+
+func TestSafeMethodArgs_ListStrKwArg(t *testing.T) {
+	t.Parallel()
+
+	tcs := map[string]struct {
+		err          error
+		args         map[string]any
+		argName      string
+		defaultValue []string
+		expected     []string
+	}{
+		"valid string list": {
+			args:         map[string]any{"key": []any{"value1", "value2"}},
+			argName:      "key",
+			defaultValue: []string{"default"},
+			expected:     []string{"value1", "value2"},
+		},
+		"key does not exist": {
+			args:         map[string]any{"other_key": []any{"value"}},
+			argName:      "key",
+			defaultValue: []string{"default"},
+			expected:     []string{"default"},
+		},
+		"none value": {
+			args:         map[string]any{"key": nil},
+			argName:      "key",
+			defaultValue: []string{"default"},
+			expected:     []string{"default"},
+		},
+		"non-list value": {
+			args:    map[string]any{"key": "not a list"},
+			argName: "key",
+			err:     errors.New(`expected []string argument "key", got string`),
+		},
+		"non-string in list": {
+			args:    map[string]any{"key": []any{"value1", 123}},
+			argName: "key",
+			err:     errors.New(`expected string at index 1 of "key", got int`),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			methodArgs := &plugin.MethodArgs{
+				KwArgs: tc.args,
+			}
+			safeArgs := plugins.SafeMethodArgs{Args: methodArgs}
+
+			result, err := safeArgs.ListStrKwArg(tc.argName, tc.defaultValue)
+
+			if tc.err != nil {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err.Error())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
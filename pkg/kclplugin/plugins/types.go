@@ -7,4 +7,6 @@ const (
 	TypeStr = "str"
 	// TypeBool is the KCL boolean type identifier.
 	TypeBool = "bool"
+	// TypeInt is the KCL integer type identifier.
+	TypeInt = "int"
 )
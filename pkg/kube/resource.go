@@ -40,6 +40,21 @@ func (o Object) GetName() string {
 	return v
 }
 
+// GetNamespace returns the metadata.namespace field of the Kubernetes resource.
+func (o Object) GetNamespace() string {
+	metadata, ok := o["metadata"].(map[string]any)
+	if !ok {
+		return ""
+	}
+
+	v, ok := metadata["namespace"].(string)
+	if !ok {
+		return ""
+	}
+
+	return v
+}
+
 // DeepCopy returns a recursive deep copy of the [Object].
 func (o Object) DeepCopy() Object {
 	if o == nil {
@@ -121,6 +121,50 @@ func TestObject_GetName(t *testing.T) {
 	}
 }
 
+func TestObject_GetNamespace(t *testing.T) {
+	t.Parallel()
+
+	tcs := map[string]struct {
+		obj  kube.Object
+		want string
+	}{
+		"valid namespace": {
+			obj: kube.Object{
+				"metadata": map[string]any{
+					"name":      "my-pod",
+					"namespace": "default",
+				},
+			},
+			want: "default",
+		},
+		"missing metadata": {
+			obj:  kube.Object{},
+			want: "",
+		},
+		"missing namespace in metadata": {
+			obj: kube.Object{
+				"metadata": map[string]any{
+					"name": "my-pod",
+				},
+			},
+			want: "",
+		},
+		"nil object": {
+			obj:  nil,
+			want: "",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.obj.GetNamespace()
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
 func TestObject_DeepCopy(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,3 @@
+// Package offline controls kclipper's offline mode, in which network access is
+// refused and only previously cached charts are used.
+package offline
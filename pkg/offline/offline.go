@@ -0,0 +1,65 @@
+package offline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// EnvVar is the environment variable which enables offline mode.
+const EnvVar = "KCLIPPER_OFFLINE"
+
+// ErrOffline indicates that a network request was refused in offline mode.
+var ErrOffline = errors.New("offline mode")
+
+var enabled atomic.Bool
+
+func init() {
+	v, err := strconv.ParseBool(os.Getenv(EnvVar))
+	if err == nil {
+		enabled.Store(v)
+	}
+}
+
+// Enabled returns true if offline mode is enabled for the process. It is
+// initialized from [EnvVar], and may be changed with [SetEnabled].
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// SetEnabled enables or disables offline mode for the process.
+func SetEnabled(v bool) {
+	enabled.Store(v)
+}
+
+// CacheMissError is returned in offline mode when a resource is not cached, and
+// would otherwise need to be fetched from the network. It wraps [ErrOffline].
+type CacheMissError struct {
+	// Resource is the kind of resource that was requested, e.g. "chart".
+	Resource string
+	// Name identifies the resource, e.g. a chart name or URL.
+	Name string
+	// Version is the requested version, if any.
+	Version string
+	// Source is where the resource would have been fetched from, if any.
+	Source string
+}
+
+func (err CacheMissError) Error() string {
+	msg := fmt.Sprintf("%s: %s %q", ErrOffline, err.Resource, err.Name)
+	if err.Version != "" {
+		msg += fmt.Sprintf(" version %q", err.Version)
+	}
+
+	if err.Source != "" {
+		msg += fmt.Sprintf(" from %q", err.Source)
+	}
+
+	return msg + " is not cached"
+}
+
+func (err CacheMissError) Unwrap() error {
+	return ErrOffline
+}
@@ -0,0 +1,53 @@
+package offline_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/macropower/kclipper/pkg/offline"
+)
+
+func TestCacheMissError(t *testing.T) {
+	t.Parallel()
+
+	tcs := map[string]struct {
+		err  offline.CacheMissError
+		want string
+	}{
+		"chart": {
+			err: offline.CacheMissError{
+				Resource: "chart",
+				Name:     "podinfo",
+				Version:  "6.7.0",
+				Source:   "https://stefanprodan.github.io/podinfo",
+			},
+			want: `offline mode: chart "podinfo" version "6.7.0" from ` +
+				`"https://stefanprodan.github.io/podinfo" is not cached`,
+		},
+		"url": {
+			err: offline.CacheMissError{
+				Resource: "CRDs",
+				Name:     "https://example.com/crd.yaml",
+			},
+			want: `offline mode: CRDs "https://example.com/crd.yaml" is not cached`,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, tc.err.Error())
+
+			err := fmt.Errorf("wrapped: %w", tc.err)
+			assert.ErrorIs(t, err, offline.ErrOffline)
+
+			var missErr offline.CacheMissError
+			require.ErrorAs(t, err, &missErr)
+			assert.Equal(t, tc.err, missErr)
+		})
+	}
+}
@@ -71,7 +71,8 @@ func (p *StaticTempPaths) GetPathIfExists(key string) string {
 	return path
 }
 
-// GetPaths gets a copy of the map of paths.
+// GetPaths gets a copy of the map of paths. Entries in the root directory
+// whose names cannot be decoded are skipped.
 func (p *StaticTempPaths) GetPaths() map[string]string {
 	ds, err := os.ReadDir(p.root)
 	if err != nil {
@@ -81,8 +82,12 @@ func (p *StaticTempPaths) GetPaths() map[string]string {
 	paths := map[string]string{}
 
 	for _, d := range ds {
-		path := filepath.Join(p.root, d.Name())
-		paths[p.pathToKey(path)] = path
+		key, err := p.pe.Decode(d.Name())
+		if err != nil {
+			continue
+		}
+
+		paths[key] = filepath.Join(p.root, d.Name())
 	}
 
 	return paths
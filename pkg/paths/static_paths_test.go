@@ -115,3 +115,22 @@ func TestGetStaticPaths_no_race(t *testing.T) {
 		}()
 	}
 }
+
+func TestGetStaticPaths(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	stp := paths.NewStaticTempPaths(root, paths.NewBase64PathEncoder())
+
+	testFile, err := stp.GetPath("https://localhost/test.txt")
+	require.NoError(t, err)
+
+	err = os.WriteFile(testFile, []byte("test"), 0o600)
+	require.NoError(t, err)
+
+	// Names which are not encoded keys are skipped.
+	err = os.WriteFile(filepath.Join(root, "not-a-key!"), []byte("test"), 0o600)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"https://localhost/test.txt": testFile}, stp.GetPaths())
+}
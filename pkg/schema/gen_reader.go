@@ -12,6 +12,8 @@ import (
 	"path/filepath"
 
 	"go.jacobcolvin.com/x/jsonschema"
+
+	"github.com/macropower/kclipper/pkg/offline"
 )
 
 var (
@@ -96,7 +98,14 @@ func (g *ReaderGenerator) FromFile(path string) ([]byte, error) {
 	return g.FromData(jsBytes, baseDir)
 }
 
+// FromURL reads a JSON Schema from the given HTTP URL and returns the
+// corresponding []byte representation. Schemas are not cached, so in offline
+// mode an [offline.CacheMissError] is returned.
 func (g *ReaderGenerator) FromURL(schemaURL *url.URL) ([]byte, error) {
+	if offline.Enabled() {
+		return nil, offline.CacheMissError{Resource: "JSON Schema", Name: schemaURL.String()}
+	}
+
 	schema, err := http.DefaultClient.Do(&http.Request{
 		Method: http.MethodGet,
 		URL:    schemaURL,
@@ -6,6 +6,7 @@ import "sync"
 // See [KeyLock] for an implementation.
 type KeyLocker interface {
 	Lock(key string)
+	TryLock(key string) bool
 	Unlock(key string)
 }
 
@@ -46,6 +47,12 @@ func (kl *KeyLock) Lock(key string) {
 	kl.getLock(key).Lock()
 }
 
+// TryLock tries to acquire the mutex for the given key without blocking, and
+// reports whether it succeeded.
+func (kl *KeyLock) TryLock(key string) bool {
+	return kl.getLock(key).TryLock()
+}
+
 // Unlock releases the mutex for the given key.
 func (kl *KeyLock) Unlock(key string) {
 	kl.getLock(key).Unlock()
@@ -55,6 +55,21 @@ func TestKeyLock(t *testing.T) {
 				kl.Unlock("b")
 			})
 
+			t.Run("try lock fails while key is held", func(t *testing.T) {
+				t.Parallel()
+
+				kl := tc.newLock()
+
+				kl.Lock("a")
+				assert.False(t, kl.TryLock("a"))
+				assert.True(t, kl.TryLock("b"))
+				kl.Unlock("a")
+				kl.Unlock("b")
+
+				assert.True(t, kl.TryLock("a"))
+				kl.Unlock("a")
+			})
+
 			t.Run("same key serializes access", func(t *testing.T) {
 				t.Parallel()
 